@@ -0,0 +1,133 @@
+package tree
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindUser struct {
+	ID     int    `uri:"id" json:"id" xml:"id" yaml:"id" toml:"id" form:"id"`
+	Name   string `json:"name" xml:"name" yaml:"name" toml:"name" form:"name" v:"required"`
+	Notify bool   `query:"notify"`
+}
+
+func newBindMux() *Mux {
+	mux := InitMux()
+
+	handler := func(c *Ctx) error {
+		var u bindUser
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		return c.SendJSON(J{"id": u.ID, "name": u.Name, "notify": u.Notify}, OK)
+	}
+
+	mux.PUT("/users/:id", handler)
+	mux.GET("/users/:id", handler)
+	mux.DELETE("/users/:id", handler)
+
+	return mux
+}
+
+func TestCtx_Bind(t *testing.T) {
+	mux := newBindMux()
+
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json", `{"id":99,"name":"tree"}`},
+		{"application/xml", `<bindUser><id>99</id><name>tree</name></bindUser>`},
+		{"application/x-yaml", "id: 99\nname: tree\n"},
+		{"application/toml", "id = 99\nname = \"tree\"\n"},
+		{"application/x-www-form-urlencoded", "id=99&name=tree"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("PUT", "/users/7?notify=true", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		// the route param wins over the id sent in the body
+		expected := `{"id":7,"name":"tree","notify":true}`
+		if w.Code != OK || strings.TrimSpace(w.Body.String()) != expected {
+			t.Errorf("%s: expected 200 %s, got %d %s", tt.contentType, expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCtx_BindFormDELETE(t *testing.T) {
+	mux := newBindMux()
+
+	req := httptest.NewRequest("DELETE", "/users/7?notify=true", strings.NewReader("name=tree"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	expected := `{"id":7,"name":"tree","notify":true}`
+	if w.Code != OK || strings.TrimSpace(w.Body.String()) != expected {
+		t.Errorf("Expected 200 %s, got %d %s", expected, w.Code, w.Body.String())
+	}
+}
+
+func TestCtx_BindFormTwice(t *testing.T) {
+	type tags struct {
+		Tags []string `form:"tag"`
+	}
+
+	mux := InitMux()
+	mux.DELETE("/tags", func(c *Ctx) error {
+		var first, second tags
+		if err := c.BindForm(&first); err != nil {
+			return err
+		}
+		if err := c.Bind(&second); err != nil {
+			return err
+		}
+		return c.SendString(strings.Join(first.Tags, ",")+" "+strings.Join(second.Tags, ","), OK)
+	})
+
+	req := httptest.NewRequest("DELETE", "/tags", strings.NewReader("tag=a&tag=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.String() != "a,b a,b" {
+		t.Errorf("Expected the form values once per bind, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestCtx_BindErrors(t *testing.T) {
+	mux := newBindMux()
+	mux.SetMaxBodyBytes(256)
+
+	tests := []struct {
+		method      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"PUT", "application/octet-stream", "raw", UnsupportedMediaType},
+		{"PUT", "", "raw", UnsupportedMediaType},
+		{"PUT", "application/json", `{"id":1}`, BadRequest},
+		{"PUT", "application/json", `{"name":`, BadRequest},
+		{"PUT", "application/xml", `<bindUser><id>x</id></bindUser>`, BadRequest},
+		{"PUT", "application/json", `{"name":"` + strings.Repeat("a", 300) + `"}`, RequestEntityTooLarge},
+		{"GET", "", "", BadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/users/7", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s %q %q: expected %d, got %d (%s)", tt.method, tt.contentType, tt.body, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
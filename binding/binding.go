@@ -1,6 +1,10 @@
 package binding
 
-import "net/http"
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
 
 type Binding interface {
 	Name() string
@@ -28,9 +32,43 @@ var (
 	Query QueryBindingInterface = &QueryBinding{}
 )
 
-func Default(method, contentType string) string {
-	if method == http.MethodGet {
-		return "form"
+// Default returns the Binding decoding a request body of contentType,
+// or nil when the media type isn't supported. GET and HEAD requests have
+// no body to bind, Default returns nil for them.
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	switch mediaType {
+	case "application/json":
+		return JSON
+	case "application/xml", "text/xml":
+		return XML
+	case "application/x-yaml", "application/yaml", "text/yaml":
+		return YAML
+	case "application/toml":
+		return TOML
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return Form
+	case "text/plain":
+		return Text
+	}
+
+	// structured syntax suffixes, e.g. application/problem+json
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSON
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XML
+	case strings.HasSuffix(mediaType, "+yaml"):
+		return YAML
 	}
-	return "test"
+
+	return nil
 }
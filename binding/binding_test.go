@@ -0,0 +1,63 @@
+package binding
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		method      string
+		contentType string
+		expected    Binding
+	}{
+		{http.MethodPost, "application/json", JSON},
+		{http.MethodPut, "application/json; charset=utf-8", JSON},
+		{http.MethodPost, "application/problem+json", JSON},
+		{http.MethodPost, "application/xml", XML},
+		{http.MethodPost, "text/xml; charset=utf-8", XML},
+		{http.MethodPost, "application/atom+xml", XML},
+		{http.MethodPost, "application/x-yaml", YAML},
+		{http.MethodPost, "application/yaml", YAML},
+		{http.MethodPost, "application/toml", TOML},
+		{http.MethodPost, "application/x-www-form-urlencoded", Form},
+		{http.MethodPatch, "multipart/form-data; boundary=xyz", Form},
+		{http.MethodPost, "text/plain", Text},
+		{http.MethodPost, "application/octet-stream", nil},
+		{http.MethodPost, "", nil},
+		{http.MethodPost, "not a media type;;", nil},
+		{http.MethodGet, "application/json", nil},
+		{http.MethodHead, "application/json", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Default(tt.method, tt.contentType); got != tt.expected {
+			t.Errorf("Default(%s, %q) = %v, expected %v", tt.method, tt.contentType, got, tt.expected)
+		}
+	}
+}
+
+func TestMapQueryAndURI(t *testing.T) {
+	type filter struct {
+		ID    int    `uri:"id"`
+		Page  int    `query:"page"`
+		Sort  string `query:"sort"`
+		Other string
+	}
+
+	var f filter
+	if err := MapURI(map[string]string{"id": "7"}, &f); err != nil {
+		t.Fatalf("MapURI: %v", err)
+	}
+	if err := MapQuery(map[string][]string{"page": {"2"}, "sort": {"name"}, "Other": {"x"}}, &f); err != nil {
+		t.Fatalf("MapQuery: %v", err)
+	}
+
+	if f != (filter{ID: 7, Page: 2, Sort: "name"}) {
+		t.Errorf("Unexpected result: %+v", f)
+	}
+
+	if err := MapURI(map[string]string{"id": "7"}, f); err == nil {
+		t.Error("Expected an error for a non pointer destination")
+	}
+}
@@ -3,6 +3,7 @@ package binding
 import (
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -28,17 +29,13 @@ func (FormBinding) Bind(req *http.Request, v any) error {
 		return errors.New("content type is missing")
 	}
 
-	if req.Method != http.MethodPost {
-		return errors.New("method not allowed")
-	}
-
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		if err := req.ParseMultipartForm(defaultMemory); err != nil {
-			return errors.New("error parsing multipart form: " + err.Error())
+			return fmt.Errorf("error parsing multipart form: %w", err)
 		}
 	} else {
-		if err := req.ParseForm(); err != nil {
-			return errors.New("error parsing form: " + err.Error())
+		if err := parseForm(req); err != nil {
+			return fmt.Errorf("error parsing form: %w", err)
 		}
 	}
 
@@ -47,16 +44,54 @@ func (FormBinding) Bind(req *http.Request, v any) error {
 	}
 
 	if err := decodeForm(req, v); err != nil {
-		return errors.New("error decoding form: " + err.Error())
+		return fmt.Errorf("error decoding form: %w", err)
 	}
 
 	return validator.Validate(v)
 }
 
+// parseForm parses the form like http.Request.ParseForm, also reading the
+// urlencoded body of the methods it ignores, e.g. DELETE.
+func parseForm(req *http.Request) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return nil
+	}
+
+	// the body was parsed by an earlier call, don't add its values twice
+	if len(req.PostForm) > 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, defaultMemory+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > defaultMemory {
+		return errors.New("form body too large")
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		req.PostForm[key] = append(req.PostForm[key], value...)
+		req.Form[key] = append(value, req.Form[key]...)
+	}
+
+	return nil
+}
+
 func decodeForm(req *http.Request, v any) error {
 	err := mapForm(req, v)
 	if err != nil {
-		return errors.New("error decoding form: " + err.Error())
+		return fmt.Errorf("error decoding form: %w", err)
 	}
 
 	return validator.Validate(v)
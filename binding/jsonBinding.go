@@ -32,7 +32,7 @@ func (JSONBinding) BindBody(data []byte, v any) any {
 func decodeJSON(r io.Reader, v any) error {
 	decoder := json.NewDecoder(r)
 	if err := decoder.Decode(v); err != nil {
-		return errors.New("error decoding json: " + err.Error())
+		return fmt.Errorf("error decoding json: %w", err)
 	}
 	return validator.Validate(v)
 }
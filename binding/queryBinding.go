@@ -25,14 +25,23 @@ func (QueryBinding) BindQuery(req *http.Request, v any) error {
 
 func decodeQuery(query map[string][]string, v any) error {
 	fmt.Println(query)
-	err := bindMap(query, v)
+	err := bindMap(query, v, true)
 	if err != nil {
-		return errors.New("error decoding query: " + err.Error())
+		return fmt.Errorf("error decoding query: %w", err)
 	}
 	return validator.Validate(v)
 }
 
-func bindMap(m map[string][]string, v any) error {
+// MapQuery sets the fields of v tagged with `query` from values, without
+// validating v. Fields without the tag are left untouched.
+func MapQuery(values map[string][]string, v any) error {
+	if err := bindMap(values, v, false); err != nil {
+		return fmt.Errorf("error decoding query: %w", err)
+	}
+	return nil
+}
+
+func bindMap(m map[string][]string, v any, warn bool) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return errors.New("v must be a pointer")
@@ -55,6 +64,9 @@ func bindMap(m map[string][]string, v any) error {
 
 		queryTag := fieldType.Tag.Get("query")
 		if queryTag == "" {
+			if !warn {
+				continue
+			}
 			fmt.Printf("[WARNING] Query tag not found for field: %s. Used field name for this.", fieldType.Name)
 			continue
 		}
@@ -2,6 +2,7 @@ package binding
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"reflect"
@@ -24,7 +25,7 @@ func (TextBinding) Bind(req *http.Request, v any) error {
 func decodeText(r io.Reader, v any) error {
 	n, err := io.ReadAll(r)
 	if err != nil {
-		return errors.New("error decoding text: " + err.Error())
+		return fmt.Errorf("error decoding text: %w", err)
 	}
 
 	refVal := reflect.ValueOf(v)
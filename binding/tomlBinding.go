@@ -2,6 +2,7 @@ package binding
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -26,7 +27,7 @@ func decodeTOML(r io.Reader, v any) error {
 
 	decoder := toml.NewDecoder(r)
 	if _, err := decoder.Decode(v); err != nil {
-		return errors.New("error decoding xml: " + err.Error())
+		return fmt.Errorf("error decoding toml: %w", err)
 	}
 	validator.Validate(v)
 	return validator.Validate(v)
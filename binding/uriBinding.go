@@ -1,6 +1,7 @@
 package binding
 
 import (
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,14 +28,28 @@ func (URIBinding) BindURI(params map[string]string, v any) error {
 		return errors.New("v must be a struct pointer")
 	}
 
-	if err := mapUriParams(params, elem); err != nil {
-		return errors.New("error binding uri: " + err.Error())
+	if err := mapUriParams(params, elem, true); err != nil {
+		return fmt.Errorf("error binding uri: %w", err)
 	}
 
 	return validator.Validate(v)
 }
 
-func mapUriParams(params map[string]string, structVal reflect.Value) error {
+// MapURI sets the fields of v tagged with `uri` from the route params,
+// without validating v. Fields without the tag are left untouched.
+func MapURI(params map[string]string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("v must be a struct pointer")
+	}
+
+	if err := mapUriParams(params, rv.Elem(), false); err != nil {
+		return fmt.Errorf("error binding uri: %w", err)
+	}
+	return nil
+}
+
+func mapUriParams(params map[string]string, structVal reflect.Value, warn bool) error {
 	structType := structVal.Type()
 
 	for i := 0; i < structType.NumField(); i++ {
@@ -47,6 +62,9 @@ func mapUriParams(params map[string]string, structVal reflect.Value) error {
 
 		uriTag := fieldType.Tag.Get("uri")
 		if uriTag == "" {
+			if !warn {
+				continue
+			}
 			fmt.Println("[WARNING] URI tag not found for field:", fieldType.Name)
 			continue
 		}
@@ -136,6 +154,13 @@ func setFieldValue(field reflect.Value, paramValue string) error {
 	case reflect.Map:
 		err := json.Unmarshal(StringToBytes(paramValue), field.Addr().Interface())
 		printError(err, paramValue, "map")
+	case reflect.Array:
+		// e.g. uuid.UUID, matching a ":id<uuid>" route param
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			err := u.UnmarshalText(StringToBytes(paramValue))
+			printError(err, paramValue, field.Type().String())
+			return err
+		}
 	default:
 		if field.Type() == reflect.TypeOf((*multipart.FileHeader)(nil)) || field.Type() == reflect.TypeOf(multipart.FileHeader{}) {
 			return fmt.Errorf("file header type not supported")
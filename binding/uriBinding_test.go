@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestURIBinding_Name(t *testing.T) {
@@ -568,6 +570,24 @@ func TestSetFieldValue(t *testing.T) {
 			t.Errorf("Expected pointer to 'test', got %v", ptr)
 		}
 	})
+
+	t.Run("UUIDField", func(t *testing.T) {
+		var id uuid.UUID
+		field := reflect.ValueOf(&id).Elem()
+
+		err := setFieldValue(field, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+		if err != nil {
+			t.Errorf("Expected no error, got: %s", err.Error())
+		}
+
+		if id.String() != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+			t.Errorf("Expected parsed UUID, got %s", id)
+		}
+
+		if err := setFieldValue(field, "not-a-uuid"); err == nil {
+			t.Error("Expected error for an invalid UUID")
+		}
+	})
 }
 
 // Benchmark tests
@@ -16,6 +16,12 @@ type Validator struct{}
 
 var validator Validator = Validator{}
 
+// Validate checks the `v` tags, e.g. `v:"required"`, of the struct pointed
+// to by v.
+func Validate(v any) error {
+	return validator.Validate(v)
+}
+
 func (Validator) Validate(v any) error {
 	rv := reflect.ValueOf(v)
 
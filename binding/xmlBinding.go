@@ -3,6 +3,7 @@ package binding
 import (
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 )
@@ -24,7 +25,7 @@ func (XMLBinding) Bind(req *http.Request, v any) error {
 func decodeXML(r io.Reader, v any) error {
 	decoder := xml.NewDecoder(r)
 	if err := decoder.Decode(v); err != nil {
-		return errors.New("error decoding xml: " + err.Error())
+		return fmt.Errorf("error decoding xml: %w", err)
 	}
 	return validator.Validate(v)
 }
@@ -2,6 +2,7 @@ package binding
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -26,7 +27,7 @@ func decodeYAML(r io.Reader, v any) error {
 	decoder := yaml.NewDecoder(r)
 
 	if err := decoder.Decode(v); err != nil {
-		return errors.New("error decoding yaml: " + err.Error())
+		return fmt.Errorf("error decoding yaml: %w", err)
 	}
 	return validator.Validate(v)
 }
@@ -0,0 +1,119 @@
+package tree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// bodyCache holds the request body once it has been read through the Ctx,
+// in memory up to the maxMemory of the Ctx and in a temporary file beyond.
+type bodyCache struct {
+	cached bool
+	err    error
+	data   []byte
+	file   *os.File
+	size   int64
+}
+
+// reset drops the cached body and removes its temporary file.
+func (b *bodyCache) reset() {
+	if b.file != nil {
+		b.file.Close()
+		os.Remove(b.file.Name())
+	}
+
+	*b = bodyCache{}
+}
+
+// ReleaseBody removes the temporary file holding a large request body read
+// through the Ctx. The Mux does it once the request is done, a Ctx created
+// with NewCtx has to call it when it isn't used anymore.
+func (c *Ctx) ReleaseBody() {
+	c.body.reset()
+}
+
+// cacheBody reads the whole request body once. A read error, like the
+// *http.MaxBytesError of a body over the limit, is kept and returned by
+// every later call.
+func (c *Ctx) cacheBody() error {
+	b := &c.body
+	if b.cached {
+		return b.err
+	}
+	b.cached = true
+
+	if c.r.Body == nil || c.r.Body == http.NoBody {
+		return nil
+	}
+	defer c.r.Body.Close()
+
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(c.r.Body, c.maxMemory+1))
+	if err != nil {
+		b.err = fmt.Errorf("failed to read request body: %w", err)
+		return b.err
+	}
+
+	if n <= c.maxMemory {
+		b.data = buf.Bytes()
+		b.size = n
+		return nil
+	}
+
+	// the body doesn't fit in memory, spill it to a temporary file
+	f, err := os.CreateTemp("", "tree-body-*")
+	if err != nil {
+		b.err = fmt.Errorf("failed to buffer request body: %w", err)
+		return b.err
+	}
+	b.file = f
+
+	size, err := io.Copy(f, io.MultiReader(&buf, c.r.Body))
+	if err != nil {
+		b.err = fmt.Errorf("failed to read request body: %w", err)
+		return b.err
+	}
+	b.size = size
+
+	return nil
+}
+
+// rewindBody caches the body if needed and points c.r.Body to its start,
+// so the bindings and net/http form parsing can read it again.
+func (c *Ctx) rewindBody() error {
+	if err := c.cacheBody(); err != nil {
+		return err
+	}
+
+	b := &c.body
+	switch {
+	case b.file != nil:
+		c.r.Body = io.NopCloser(io.NewSectionReader(b.file, 0, b.size))
+	case c.r.Body != nil && c.r.Body != http.NoBody:
+		c.r.Body = io.NopCloser(bytes.NewReader(b.data))
+	}
+
+	return nil
+}
+
+// bodyBytes returns the cached body, loading it from the temporary file
+// when it was spilled to disk.
+func (c *Ctx) bodyBytes() ([]byte, error) {
+	if err := c.cacheBody(); err != nil {
+		return nil, err
+	}
+
+	b := &c.body
+	if b.file == nil {
+		return b.data, nil
+	}
+
+	data := make([]byte, b.size)
+	if _, err := b.file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return data, nil
+}
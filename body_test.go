@@ -0,0 +1,186 @@
+package tree
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCtx_BodyReread(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	var logged string
+
+	mux := InitMux()
+	mux.USE("/", func(c *Ctx) error {
+		body, err := c.Body()
+		if err != nil {
+			return err
+		}
+		logged = string(body)
+		return c.Next()
+	})
+	mux.POST("/users", func(c *Ctx) error {
+		var p payload
+		if err := c.BindJSON(&p); err != nil {
+			return err
+		}
+
+		parsed, err := c.BodyParsed()
+		if err != nil {
+			return err
+		}
+		return c.SendString(p.Name+" "+parsed["name"].(string), OK)
+	})
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"tree"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.String() != "tree tree" {
+		t.Errorf("Expected the handler to bind the body twice, got %d %q", w.Code, w.Body.String())
+	}
+	if logged != `{"name":"tree"}` {
+		t.Errorf("Expected the middleware to read the body, got %q", logged)
+	}
+}
+
+func TestCtx_BodyFormAfterRead(t *testing.T) {
+	mux := InitMux()
+	mux.POST("/form", func(c *Ctx) error {
+		if _, err := c.Body(); err != nil {
+			return err
+		}
+		if err := c.ParseForm(); err != nil {
+			return err
+		}
+		return c.SendString(c.GetRequest().PostForm.Get("name"), OK)
+	})
+
+	req := httptest.NewRequest("POST", "/form", strings.NewReader("name=tree"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.String() != "tree" {
+		t.Errorf("Expected the form to be parsed after reading the body, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestCtx_BodySpillsToFile(t *testing.T) {
+	var tempFile string
+	content := strings.Repeat("0123456789", 100)
+
+	mux := InitMux()
+	mux.POST("/upload", func(c *Ctx) error {
+		c.SetMaxMemParsed(64)
+
+		first, err := c.Body()
+		if err != nil {
+			return err
+		}
+		if c.body.file == nil {
+			return errors.New("expected the body to be spilled to a file")
+		}
+		tempFile = c.body.file.Name()
+
+		second, err := c.Body()
+		if err != nil {
+			return err
+		}
+		if string(first) != string(second) {
+			return errors.New("expected the body to be read twice")
+		}
+
+		var text string
+		if err := c.BindPlaintext(&text); err != nil {
+			return err
+		}
+		return c.SendString(text, OK)
+	})
+
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(content))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.String() != content {
+		t.Fatalf("Expected the spilled body to be bound, got %d (%d bytes)", w.Code, w.Body.Len())
+	}
+	if _, err := os.Stat(tempFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the temporary file %s to be removed, got %v", tempFile, err)
+	}
+}
+
+func TestCtx_BodyErrorIsKept(t *testing.T) {
+	mux := InitMux()
+	mux.SetMaxBodyBytes(8)
+	mux.POST("/echo", func(c *Ctx) error {
+		_, first := c.Body()
+		_, second := c.Body()
+
+		var maxErr *http.MaxBytesError
+		if !errors.As(first, &maxErr) || !errors.As(second, &maxErr) {
+			return errors.New("expected both reads to fail with the limit error")
+		}
+		return first
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("way too long")))
+
+	if w.Code != RequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestCtx_BodyFileRemovedOnPanic(t *testing.T) {
+	var tempFile string
+
+	mux := InitMux()
+	mux.POST("/upload", func(c *Ctx) error {
+		c.SetMaxMemParsed(8)
+		if _, err := c.Body(); err != nil {
+			return err
+		}
+		tempFile = c.body.file.Name()
+		panic("boom")
+	})
+
+	func() {
+		defer func() { recover() }()
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", strings.NewReader("larger than eight bytes")))
+	}()
+
+	if tempFile == "" {
+		t.Fatal("Expected the body to be spilled to a file")
+	}
+	if _, err := os.Stat(tempFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the temporary file %s to be removed, got %v", tempFile, err)
+	}
+}
+
+func TestCtx_ReleaseBody(t *testing.T) {
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader("larger than eight bytes"))
+	c := NewCtx(httptest.NewRecorder(), req, "/upload", nil, nil, false)
+	c.SetMaxMemParsed(8)
+
+	if _, err := c.Body(); err != nil {
+		t.Fatal(err)
+	}
+	if c.body.file == nil {
+		t.Fatal("Expected the body to be spilled to a file")
+	}
+	tempFile := c.body.file.Name()
+
+	c.ReleaseBody()
+	if _, err := os.Stat(tempFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the temporary file %s to be removed, got %v", tempFile, err)
+	}
+}
@@ -0,0 +1,36 @@
+package tree
+
+import (
+	"net/http"
+)
+
+// SetMaxBodyBytes limits the size of the request bodies read through the
+// Ctx, zero means no limit. Reading past the limit fails with an
+// *http.MaxBytesError, which DefaultErrorHandler answers with 413 Request
+// Entity Too Large. StartExecuting overrides it with
+// ServerConfig.MaxBodyBytes when that one is set.
+func (r *Mux) SetMaxBodyBytes(n int64) {
+	r.maxBodyBytes = n
+}
+
+// MaxBodyBytes overrides the body size limit of the Mux for the route,
+// e.g. to accept larger uploads. A negative value removes the limit.
+func (rt *Route) MaxBodyBytes(n int64) *Route {
+	rt.maxBodyBytes = n
+	return rt
+}
+
+// limitBody wraps the request body with http.MaxBytesReader using the
+// limit of the route, or the one of the Mux when the route has none.
+func (r *Mux) limitBody(c *Ctx, routeLimit int64) {
+	limit := r.maxBodyBytes
+	if routeLimit != 0 {
+		limit = routeLimit
+	}
+
+	if limit <= 0 || c.r.Body == nil || c.r.Body == http.NoBody {
+		return
+	}
+
+	c.r.Body = http.MaxBytesReader(c.w, c.r.Body, limit)
+}
@@ -0,0 +1,103 @@
+package tree
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMux_MaxBodyBytes(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	mux := InitMux()
+	mux.SetMaxBodyBytes(32)
+
+	echo := func(c *Ctx) error {
+		body, err := c.Body()
+		if err != nil {
+			return err
+		}
+		return c.SendString(string(body), OK)
+	}
+
+	mux.POST("/echo", echo)
+	mux.POST("/upload", echo).MaxBodyBytes(1024)
+	mux.POST("/unlimited", echo).MaxBodyBytes(-1)
+	mux.POST("/json", func(c *Ctx) error {
+		var p payload
+		if err := c.BindJSON(&p); err != nil {
+			return err
+		}
+		return c.SendString(p.Name, OK)
+	})
+	mux.POST("/form", func(c *Ctx) error {
+		if err := c.ParseForm(); err != nil {
+			return err
+		}
+		return c.SendString(c.GetRequest().PostForm.Get("name"), OK)
+	})
+
+	large := strings.Repeat("a", 100)
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"/echo", "text/plain", "small", OK},
+		{"/echo", "text/plain", large, RequestEntityTooLarge},
+		{"/upload", "text/plain", large, OK},
+		{"/upload", "text/plain", strings.Repeat("a", 2000), RequestEntityTooLarge},
+		{"/unlimited", "text/plain", strings.Repeat("a", 2000), OK},
+		{"/json", "application/json", `{"name":"tree"}`, OK},
+		{"/json", "application/json", `{"name":"` + large + `"}`, RequestEntityTooLarge},
+		{"/form", "application/x-www-form-urlencoded", "name=tree", OK},
+		{"/form", "application/x-www-form-urlencoded", "name=" + large, RequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s with %d bytes: expected %d, got %d (%s)", tt.path, len(tt.body), tt.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMux_MaxBodyBytesWithDecompress(t *testing.T) {
+	mux := InitMux()
+	mux.SetMaxBodyBytes(64)
+	mux.USE("/", Decompress())
+	mux.POST("/echo", func(c *Ctx) error {
+		body, err := c.Body()
+		if err != nil {
+			return err
+		}
+		return c.SendString(string(body), OK)
+	})
+
+	// the limit applies to the bytes received, not to the decompressed ones
+	req := httptest.NewRequest("POST", "/echo", gzipBody(t, strings.Repeat("a", 1000)))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.Len() != 1000 {
+		t.Errorf("Expected the small compressed body to pass, got %d (%d bytes)", w.Code, w.Body.Len())
+	}
+
+	random := strings.Repeat("0123456789abcdefghijklmnopqrstuvwxyz", 50)
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(random))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != RequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large body, got %d", w.Code)
+	}
+}
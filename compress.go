@@ -0,0 +1,343 @@
+package tree
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Encoder creates a writer compressing to w. Level 0 asks for the default
+// level of the encoding.
+type Encoder func(w io.Writer, level int) (io.WriteCloser, error)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		"gzip": func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			return gzip.NewWriterLevel(w, level)
+		},
+		// the deflate content coding is the zlib format (RFC 9110)
+		"deflate": func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = zlib.DefaultCompression
+			}
+			return zlib.NewWriterLevel(w, level)
+		},
+	}
+)
+
+// RegisterEncoder makes a content coding, e.g. "br" or "zstd", available
+// to Compress. gzip and deflate are registered by default.
+func RegisterEncoder(name string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[strings.ToLower(name)] = encoder
+}
+
+func lookupEncoder(name string) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	encoder, ok := encoders[strings.ToLower(name)]
+	return encoder, ok
+}
+
+type CompressConfig struct {
+	// Encodings lists the registered encodings offered to the client, in
+	// the order of preference of the server. gzip and deflate by default.
+	Encodings []string
+	// Level is passed to the encoder, 0 for its default level.
+	Level int
+	// MinLength is the minimum body size compressed, 1024 by default.
+	// Smaller bodies are sent as they are.
+	MinLength int
+	// ContentTypes lists the compressed media types. An entry ending with
+	// "/" matches all the subtypes, e.g. "text/".
+	ContentTypes []string
+	Skipper      func(*Ctx) bool
+}
+
+// Compress compresses the response body with the encoding negotiated
+// through Ctx.AcceptEncoding. Responses are left untouched when they are
+// smaller than MinLength, their content type isn't listed, they already
+// have a Content-Encoding or they answer a range request, so SendFile
+// serves byte ranges of the original file.
+func Compress(config ...CompressConfig) func(*Ctx) error {
+	cfg := UseDefaultCompress()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{"gzip", "deflate"}
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 1024
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = UseDefaultCompress().ContentTypes
+	}
+
+	return func(c *Ctx) (err error) {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+
+		addVary(c.HeaderSent(), "Accept-Encoding")
+
+		if c.Header().Get("Range") != "" {
+			return c.Next()
+		}
+
+		encoding := c.AcceptEncoding(cfg.Encodings...)
+		encoder, ok := lookupEncoder(encoding)
+		if !ok {
+			return c.Next()
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.w.ResponseWriter,
+			cfg:            &cfg,
+			encoding:       encoding,
+			encoder:        encoder,
+			status:         OK,
+			out:            &countWriter{w: c.w.ResponseWriter},
+		}
+		c.w.ResponseWriter = cw
+
+		returned := false
+		defer func() {
+			// an error or a panic before anything was written drops the
+			// buffer, so the error handler sends the real status
+			if c.Written() || (returned && err == nil) {
+				if closeErr := cw.Close(); err == nil {
+					err = closeErr
+				}
+				c.w.size = cw.out.n
+			}
+			c.w.ResponseWriter = cw.ResponseWriter
+		}()
+
+		err = c.Next()
+		returned = true
+
+		return err
+	}
+}
+
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, value) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", value)
+}
+
+// compressWriter buffers the body until MinLength bytes are written, then
+// decides whether the response is compressed and sends the header.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *CompressConfig
+	encoding string
+	encoder  Encoder
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     io.WriteCloser
+	out     *countWriter
+}
+
+// countWriter counts the bytes sent to the client, after compression.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+
+	w.status = code
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.cfg.MinLength {
+			return len(b), nil
+		}
+
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.out.Write(b)
+}
+
+// decide sends the header, compressing the response if it qualifies, and
+// writes the buffered body.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	if compress && w.compressible(header) {
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		header.Set("Content-Encoding", w.encoding)
+
+		enc, err := w.encoder(w.out, w.cfg.Level)
+		if err != nil {
+			return err
+		}
+		w.enc = enc
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.out.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) compressible(header http.Header) bool {
+	if w.status < OK || w.status == NoContent || w.status == PartialContent || w.status == NotModified {
+		return false
+	}
+
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range w.cfg.ContentTypes {
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(mediaType, allowed) {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Close sends what is left of the response. Bodies smaller than
+// MinLength are sent uncompressed.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= w.cfg.MinLength); err != nil {
+			return err
+		}
+	}
+
+	if w.enc != nil {
+		return w.enc.Close()
+	}
+	return nil
+}
+
+// Flush sends the buffered body right away, compressed if it qualifies,
+// so streamed responses aren't held back until MinLength is reached.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+func (w *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func UseDefaultCompress() CompressConfig {
+	return CompressConfig{
+		Encodings: []string{"gzip", "deflate"},
+		Level:     0,
+		MinLength: 1024,
+		ContentTypes: []string{
+			"text/",
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"application/x-yaml",
+			"application/yaml",
+			"application/toml",
+			"image/svg+xml",
+		},
+	}
+}
@@ -0,0 +1,316 @@
+package tree
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCompressMux(cfg ...CompressConfig) *Mux {
+	mux := InitMux()
+	mux.USE("/", Compress(cfg...))
+
+	mux.GET("/large", func(c *Ctx) error {
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		return c.SendString(strings.Repeat("tree ", 1000), OK)
+	})
+	mux.GET("/small", func(c *Ctx) error {
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		return c.SendString("tiny", OK)
+	})
+	mux.GET("/image", func(c *Ctx) error {
+		c.SetHeader("Content-Type", "image/png")
+		return c.SendString(strings.Repeat("x", 4096), OK)
+	})
+
+	return mux
+}
+
+func TestCompress_Gzip(t *testing.T) {
+	mux := newCompressMux()
+
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+	}
+
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	if string(body) != strings.Repeat("tree ", 1000) {
+		t.Errorf("Unexpected decompressed body of %d bytes", len(body))
+	}
+}
+
+func TestCompress_PartialConfig(t *testing.T) {
+	mux := newCompressMux(CompressConfig{Level: 9})
+
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected the default content types to be compressed, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	if string(body) != strings.Repeat("tree ", 1000) {
+		t.Errorf("Unexpected decompressed body of %d bytes", len(body))
+	}
+}
+
+func TestCompress_Deflate(t *testing.T) {
+	mux := newCompressMux()
+
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0, deflate")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("Expected deflate encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	r, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Invalid deflate body: %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	if string(body) != strings.Repeat("tree ", 1000) {
+		t.Errorf("Unexpected decompressed body of %d bytes", len(body))
+	}
+}
+
+func TestCompress_Skipped(t *testing.T) {
+	mux := newCompressMux()
+
+	tests := []struct {
+		path     string
+		encoding string
+	}{
+		{"/small", "gzip"},
+		{"/image", "gzip"},
+		{"/large", ""},
+		{"/large", "identity"},
+		{"/large", "br"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.encoding != "" {
+			req.Header.Set("Accept-Encoding", tt.encoding)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s with %q: expected no compression, got %q", tt.path, tt.encoding, w.Header().Get("Content-Encoding"))
+		}
+		if w.Code != OK || w.Body.Len() == 0 {
+			t.Errorf("%s with %q: expected the plain body, got %d (%d bytes)", tt.path, tt.encoding, w.Code, w.Body.Len())
+		}
+	}
+}
+
+func TestCompress_RegisterEncoder(t *testing.T) {
+	RegisterEncoder("x-test", func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	})
+
+	mux := newCompressMux(CompressConfig{
+		Encodings:    []string{"x-test", "gzip"},
+		ContentTypes: []string{"text/plain"},
+	})
+
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "x-test")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "x-test" {
+		t.Errorf("Expected the registered encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompress_SendFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	content := strings.Repeat("0123456789", 500)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := InitMux()
+	mux.USE("/", Compress())
+	mux.GET("/file", func(c *Ctx) error {
+		return c.SendFile(path)
+	})
+
+	req := httptest.NewRequest("GET", "/file", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+		t.Errorf("Expected a gzip body without Content-Length, got %v", w.Header())
+	}
+
+	// ranges are served from the original file
+	req = httptest.NewRequest("GET", "/file", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=10-19")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent || w.Body.String() != "0123456789" {
+		t.Errorf("Expected 206 with the plain range, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Content-Range") != "bytes 10-19/5000" {
+		t.Errorf("Unexpected range headers: %v", w.Header())
+	}
+}
+
+func TestCompress_Flush(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", Compress())
+	mux.GET("/stream", func(c *Ctx) error {
+		c.SetHeader("Content-Type", "text/event-stream")
+		c.Status(OK)
+		if _, err := c.ResponseWriter().Write([]byte("data: 1\n\n")); err != nil {
+			return err
+		}
+		c.ResponseWriter().(http.Flusher).Flush()
+		return nil
+	})
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if !w.Flushed || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a flushed gzip stream, got %v", w.Header())
+	}
+
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	if string(body) != "data: 1\n\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestCompress_ErrorStatus(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", Recover(RecoverConfig{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}))
+	mux.USE("/", Compress())
+	mux.GET("/missing", func(c *Ctx) error {
+		return NewHTTPError(NotFound, "nope")
+	})
+	mux.GET("/panic", func(c *Ctx) error {
+		panic("boom")
+	})
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/missing", NotFound},
+		{"/panic", InternalError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"code":`) {
+			t.Errorf("%s: expected the error envelope, got %q", tt.path, w.Body.String())
+		}
+	}
+}
+
+func TestCompress_BytesWritten(t *testing.T) {
+	var written int64
+
+	mux := InitMux()
+	mux.USE("/", func(c *Ctx) error {
+		err := c.Next()
+		written = c.BytesWritten()
+		return err
+	})
+	mux.USE("/", Compress())
+	mux.GET("/large", func(c *Ctx) error {
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		return c.SendString(strings.Repeat("tree ", 1000), OK)
+	})
+
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if written != int64(w.Body.Len()) {
+		t.Errorf("Expected the %d compressed bytes to be counted, got %d", w.Body.Len(), written)
+	}
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestCompress_Push(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", Compress())
+	mux.GET("/", func(c *Ctx) error {
+		if err := c.ResponseWriter().(http.Pusher).Push("/app.js", nil); err != nil {
+			return err
+		}
+		return c.SendString("index", OK)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || len(w.pushed) != 1 || w.pushed[0] != "/app.js" {
+		t.Errorf("Expected /app.js to be pushed through Compress, got %d %v", w.Code, w.pushed)
+	}
+}
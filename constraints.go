@@ -0,0 +1,74 @@
+package tree
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+var ErrUnknownConstraint = errors.New("unknown parameter constraint")
+
+// paramConstraints are the built-in constraints usable in route patterns
+// as ":name<constraint>", e.g. "/orders/:id<int>".
+var paramConstraints = map[string]func(string) bool{
+	"int":   isIntParam,
+	"uint":  isUintParam,
+	"uuid":  isUUIDParam,
+	"slug":  isSlugParam,
+	"alpha": isAlphaParam,
+	"alnum": isAlnumParam,
+}
+
+func isIntParam(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+func isUintParam(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+func isUUIDParam(s string) bool {
+	return len(s) == 36 && uuid.Validate(s) == nil
+}
+
+// isSlugParam accepts lowercase words of letters and digits separated by
+// single dashes, e.g. "hello-world-2".
+func isSlugParam(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9':
+		case ch == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphaParam(s string) bool {
+	for i := 0; i < len(s); i++ {
+		ch := s[i] | 0x20
+		if ch < 'a' || ch > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnumParam(s string) bool {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if (ch|0x20 < 'a' || ch|0x20 > 'z') && (ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
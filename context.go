@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"mime"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -16,22 +16,30 @@ import (
 
 	"github.com/catalinfl/tree-framework/binding"
 	"github.com/catalinfl/tree-framework/render"
+	"github.com/google/uuid"
 )
 
+// Ctx carries the request and response of a single request. A Ctx served
+// by a Mux is taken from a pool and reused once the request is done, so it
+// must not be kept or used from other goroutines after the handler returns.
 type Ctx struct {
 	r               *http.Request
-	w               http.ResponseWriter
+	w               *responseWriter
 	routerPath      string
+	mux             *Mux
 	keys            map[string]any
-	params          map[string]string
+	params          Params
 	middlewareIndex int
 	middlewares     []Middleware
 	handler         CtxFunc
 	automatic       bool
 	maxMemory       int64
 	formParsed      bool
+	body            bodyCache
 }
 
+// NewCtx creates a Ctx outside of a Mux, e.g. in tests. ReleaseBody must be
+// called once it is done, in case its body was spilled to a temporary file.
 func NewCtx(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -44,9 +52,12 @@ func NewCtx(
 
 	params := getParams(urlPathClient, urlServer)
 
+	rw := &responseWriter{}
+	rw.reset(w)
+
 	return &Ctx{
 		r:               r,
-		w:               w,
+		w:               rw,
 		routerPath:      urlServer,
 		keys:            make(map[string]any),
 		params:          params,
@@ -59,6 +70,28 @@ func NewCtx(
 	}
 }
 
+// reset prepares a pooled Ctx for a new request, keeping the keys map and
+// the params slice allocated.
+func (c *Ctx) reset(w http.ResponseWriter, r *http.Request, mux *Mux) {
+	clear(c.keys)
+	if c.keys == nil {
+		c.keys = make(map[string]any)
+	}
+
+	c.w.reset(w)
+	c.r = r
+	c.routerPath = ""
+	c.mux = mux
+	c.params = c.params[:0]
+	c.middlewareIndex = -1
+	c.middlewares = nil
+	c.handler = nil
+	c.automatic = mux != nil && mux.automatic
+	c.maxMemory = 10 << 20 // 10MB
+	c.formParsed = false
+	c.body.reset()
+}
+
 type SameSite int
 
 const (
@@ -139,16 +172,37 @@ func (c *Ctx) Status(code int) error {
 	return nil
 }
 
+// Next runs the next middleware of the chain and, once the chain is
+// exhausted, the route handler. All of them share the same Ctx.
+//
+// With automatic middleware enabled the remaining middlewares are run one
+// after another even if they don't call Next themselves. Otherwise every
+// middleware must call Next to pass control further down the chain.
 func (c *Ctx) Next() error {
 	c.middlewareIndex++
-	if c.middlewareIndex < len(c.middlewares) {
+	for c.middlewareIndex < len(c.middlewares) {
 		middleware := c.middlewares[c.middlewareIndex]
+		if middleware.checkPath && !pathMatch(middleware.Path, c.Path()) {
+			c.middlewareIndex++
+			continue
+		}
+
 		if middleware.Handler != nil {
 			err := middleware.Handler(c)
 			if err != nil {
 				return fmt.Errorf("middleware error: %w", err)
 			}
 		}
+
+		if !c.automatic {
+			return nil
+		}
+		c.middlewareIndex++
+	}
+
+	if c.middlewareIndex == len(c.middlewares) && c.handler != nil {
+		c.middlewareIndex++
+		return c.handler(c)
 	}
 
 	return nil
@@ -201,31 +255,16 @@ func (c *Ctx) Scheme() string {
 }
 
 func (c *Ctx) GetURLParam(param string) (string, error) {
-	urlPath := c.r.URL.Path
-	routerPath := c.routerPath
-
-	urlSplit := strings.Split(urlPath, "/")
-	routerSplit := strings.Split(routerPath, "/")
-
-	userParam := fmt.Sprintf(":%s", param)
-
-	var idParamIndex = -1
-
-	for i, rpath := range routerSplit {
-		if rpath == userParam && strings.Contains(routerPath, userParam) {
-			idParamIndex = i
-			break
-		}
+	if value, ok := c.params.Get(param); ok {
+		return value, nil
 	}
 
-	if idParamIndex != -1 {
-		valueToGet := urlSplit[idParamIndex]
-		return valueToGet, nil
-	} else {
-		return "", ErrInvalidParam
-	}
+	return "", ErrInvalidParam
 }
 
+// GetParamInt returns the param converted to int. Params declared with the
+// <int> constraint are validated while routing, so the conversion can't
+// fail for them.
 func (c *Ctx) GetParamInt(param string) (int, error) {
 	paramValue, err := c.GetURLParam(param)
 	if err != nil {
@@ -239,6 +278,21 @@ func (c *Ctx) GetParamInt(param string) (int, error) {
 	return intValue, nil
 }
 
+// GetParamUUID returns the param parsed as an UUID. Params declared with
+// the <uuid> constraint are validated while routing.
+func (c *Ctx) GetParamUUID(param string) (uuid.UUID, error) {
+	paramValue, err := c.GetURLParam(param)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := uuid.Parse(paramValue)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid parameter value for %s: %w", param, err)
+	}
+	return id, nil
+}
+
 func (c *Ctx) GetAllParamsInt() (map[string]int, error) {
 	stringParams, err := c.GetAllParams()
 	if err != nil {
@@ -269,60 +323,53 @@ func (c *Ctx) GetAllParamsInt() (map[string]int, error) {
 	return intParams, nil
 }
 
+// RegexURLParam returns the value of the n-th (1-based) regex segment of
+// the route, either unnamed ":|pattern|" or named ":name|pattern|".
+//
+// Note: regex segments are already enforced while routing, prefer
+// GetURLParam for named ones.
 func (c *Ctx) RegexURLParam(regexIndexToBeSearched int) (string, error) {
-	urlPath := c.r.URL.Path
-	routerPath := c.routerPath
-
 	if regexIndexToBeSearched <= 0 {
 		return "", errors.New("regex index must be positive")
 	}
 
-	routerSegments := strings.Split(routerPath, "/")
-	urlSegments := strings.Split(urlPath, "/")
-
 	regexCount := 0
-	regexFound := false
-	regexIndex := -1
-	regexPattern := ""
 
-	for i, segment := range routerSegments {
-		if len(segment) == 0 {
+	for _, segment := range strings.Split(c.routerPath, "/") {
+		if len(segment) == 0 || segment[0] != ':' {
 			continue
 		}
 
-		if strings.HasPrefix(segment, ":|") && strings.HasSuffix(segment, "|") {
-			regexCount++
-
-			if regexCount == regexIndexToBeSearched {
-				regexPattern = segment[2 : len(segment)-1]
-				regexIndex = i
-				regexFound = true
-				break
-			}
+		ps := parseParamSegment(segment)
+		if !ps.isRegex {
+			continue
 		}
-	}
 
-	if !regexFound || regexPattern == "" {
-		return "", ErrRegexParamDoesntExist
-	}
+		regexCount++
+		if regexCount != regexIndexToBeSearched {
+			continue
+		}
 
-	if regexIndex < 0 || regexIndex >= len(urlSegments) {
-		return "", ErrRegexParamDoesntExist
-	}
+		paramValue, ok := c.params.Get(ps.name)
+		if !ok || ps.pattern == "" {
+			return "", ErrRegexParamDoesntExist
+		}
 
-	paramValue := urlSegments[regexIndex]
+		if !useRegex(ps.pattern, paramValue) {
+			return "", ErrRegexNotRespected
+		}
 
-	if !useRegex(regexPattern, paramValue) {
-		return "", ErrRegexNotRespected
+		return paramValue, nil
 	}
 
-	return paramValue, nil
+	return "", ErrRegexParamDoesntExist
 }
 
 /* Ignore regex params */
 func (c *Ctx) GetAllParams() (map[string]string, error) {
 	params := make(map[string]string)
-	for key, value := range c.params {
+	for _, p := range c.params {
+		key, value := p.Key, p.Value
 		if !strings.HasPrefix(key, "|") && (!strings.HasSuffix(key, "|") || !hasNumberSuffix(key)) {
 			params[key] = value
 		}
@@ -338,7 +385,8 @@ func (c *Ctx) GetAllParams() (map[string]string, error) {
 func (c *Ctx) GetAllRegexParams() (map[string]string, error) {
 	params := make(map[string]string)
 
-	for key, value := range c.params {
+	for _, p := range c.params {
+		key, value := p.Key, p.Value
 		if strings.HasPrefix(key, "|") && (strings.HasSuffix(key, "|") || hasNumberSuffix(key)) {
 			verifyRegexWord := useRegex(key[1:len(key)-1], value)
 			if !verifyRegexWord {
@@ -781,17 +829,17 @@ func (c *Ctx) RemoveHeader(header string) {
 	c.w.Header().Del(header)
 }
 
+// Body returns the request body. It is read once and cached by the Ctx,
+// in memory up to GetMaxMemParsed bytes and in a temporary file beyond, so
+// later middlewares, Body calls and the Bind methods can read it again.
+//
+// Note: the returned slice is shared, don't modify it.
 func (c *Ctx) Body() ([]byte, error) {
-	body, err := io.ReadAll(c.r.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read request body: %w", err)
-	}
-
-	if err := c.r.Body.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close request body: %w", err)
+	if err := c.rewindBody(); err != nil {
+		return nil, err
 	}
 
-	return body, nil
+	return c.bodyBytes()
 }
 
 func (c *Ctx) BodyParsed() (J, error) {
@@ -977,8 +1025,9 @@ func getBaseHeader(clientCharset string) ([]acceptSpecial, error) {
 		q := 1.0
 
 		if len(clientValues) > 1 {
-			if strings.HasPrefix(clientValues[1], "q=") {
-				qParts := strings.Split(clientValues[1], "=")
+			param := strings.TrimSpace(clientValues[1])
+			if strings.HasPrefix(param, "q=") {
+				qParts := strings.Split(param, "=")
 				if len(qParts) >= 2 {
 					qValue, err := strconv.ParseFloat(strings.TrimSpace(qParts[1]), 64)
 					if err != nil {
@@ -989,13 +1038,18 @@ func getBaseHeader(clientCharset string) ([]acceptSpecial, error) {
 			}
 		}
 
+		// q=0 marks the value as not acceptable
+		if q <= 0 {
+			continue
+		}
+
 		acceptedValues = append(acceptedValues, acceptSpecial{
 			acceptHeaderValue: strings.TrimSpace(clientValues[0]),
 			q:                 q,
 		})
 	}
 
-	sort.Slice(acceptedValues, func(i, j int) bool {
+	sort.SliceStable(acceptedValues, func(i, j int) bool {
 		return acceptedValues[i].q > acceptedValues[j].q
 	})
 
@@ -1063,6 +1117,10 @@ func (c *Ctx) ParseForm() error {
 		return fmt.Errorf("form already parsed")
 	}
 
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
 		err = c.r.ParseForm()
 		if err != nil {
@@ -1163,14 +1221,9 @@ func (c *Ctx) GetBodyAsBinary() (b BinaryFile, err error) {
 		return binaryFileNil, fmt.Errorf("form is not binary")
 	}
 
-	data, err := io.ReadAll(c.r.Body)
-	if err != nil {
-		return binaryFileNil, fmt.Errorf("failed to read request body: %w", err)
-	}
-
-	err = c.r.Body.Close()
+	data, err := c.Body()
 	if err != nil {
-		return binaryFileNil, fmt.Errorf("failed to close request body: %w", err)
+		return binaryFileNil, err
 	}
 
 	var dispositionParams map[string]string
@@ -1303,10 +1356,10 @@ func (c *Ctx) FormValues(key ...string) (map[string]string, error) {
 }
 
 // SetMaxMemParsed sets the maximum memory to be used for parsing form data
-// in bytes. The default value is 10MB.
+// and for caching the request body, in bytes. The default value is 10MB.
+// Larger bodies are cached in a temporary file.
 //
-// Note: This function is only relevant for multipart/form-data requests.
-// Use before calling ParseForm()
+// Note: Use before calling ParseForm(), Body() or the Bind methods
 func (c *Ctx) SetMaxMemParsed(maxMemory int64) {
 	c.maxMemory = maxMemory
 }
@@ -1315,7 +1368,91 @@ func (c *Ctx) GetMaxMemParsed() int64 {
 	return c.maxMemory
 }
 
+// Bind fills obj from the route params (`uri` tags), the query string
+// (`query` tags) and the body, decoded with the binding.Binding matching
+// its Content-Type, then validates it. Route params take precedence over
+// the query and the body, so a body can't change the resource a route
+// points to.
+//
+// A body whose media type has no binding is rejected with a 415
+// Unsupported Media Type HTTPError, decoding and validation errors with a
+// 400 Bad Request one.
+func (c *Ctx) Bind(obj any) error {
+	if len(c.params) > 0 {
+		if err := binding.MapURI(c.params.Map(), obj); err != nil {
+			return bindError(err)
+		}
+	}
+
+	if c.r.URL.RawQuery != "" {
+		if err := binding.MapQuery(c.r.URL.Query(), obj); err != nil {
+			return bindError(err)
+		}
+	}
+
+	if !c.hasBody() {
+		return bindError(binding.Validate(obj))
+	}
+
+	contentType := c.r.Header.Get("Content-Type")
+	b := binding.Default(c.r.Method, contentType)
+	if b == nil {
+		if contentType == "" {
+			return NewHTTPError(UnsupportedMediaType, "missing Content-Type")
+		}
+		return NewHTTPError(UnsupportedMediaType, "unsupported Content-Type: "+contentType)
+	}
+
+	if err := c.rewindBody(); err != nil {
+		return bindError(err)
+	}
+	if err := b.Bind(c.r, obj); err != nil {
+		return bindError(err)
+	}
+
+	if len(c.params) > 0 {
+		if err := binding.MapURI(c.params.Map(), obj); err != nil {
+			return bindError(err)
+		}
+		return bindError(binding.Validate(obj))
+	}
+
+	return nil
+}
+
+// bindError reports a decoding or validation error of Bind as a 400
+// HTTPError. Errors already carrying a status, like an HTTPError from
+// Decompress or the *http.MaxBytesError of a body over the limit, are
+// returned as they are.
+func bindError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var httpErr *HTTPError
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &httpErr) || errors.As(err, &maxBytesErr) {
+		return err
+	}
+
+	return NewHTTPError(BadRequest, "", err.Error())
+}
+
+// hasBody reports whether the request carries a body to bind. GET and
+// HEAD bodies are ignored.
+func (c *Ctx) hasBody() bool {
+	if c.r.Method == http.MethodGet || c.r.Method == http.MethodHead {
+		return false
+	}
+
+	return c.r.Body != nil && c.r.Body != http.NoBody && c.r.ContentLength != 0
+}
+
 func (c *Ctx) BindJSON(obj any) error {
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	err := binding.JSON.Bind(c.r, obj)
 	if err != nil {
 		return err
@@ -1324,6 +1461,10 @@ func (c *Ctx) BindJSON(obj any) error {
 }
 
 func (c *Ctx) BindXML(obj any) error {
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	err := binding.XML.Bind(c.r, obj)
 	if err != nil {
 		return err
@@ -1332,6 +1473,10 @@ func (c *Ctx) BindXML(obj any) error {
 }
 
 func (c *Ctx) BindYAML(obj any) error {
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	err := binding.YAML.Bind(c.r, obj)
 	if err != nil {
 		return err
@@ -1340,6 +1485,10 @@ func (c *Ctx) BindYAML(obj any) error {
 }
 
 func (c *Ctx) BindTOML(obj any) error {
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	err := binding.TOML.Bind(c.r, obj)
 	if err != nil {
 		return err
@@ -1348,6 +1497,10 @@ func (c *Ctx) BindTOML(obj any) error {
 }
 
 func (c *Ctx) BindPlaintext(obj any) error {
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	err := binding.Text.Bind(c.r, obj)
 	if err != nil {
 		return err
@@ -1356,7 +1509,7 @@ func (c *Ctx) BindPlaintext(obj any) error {
 }
 
 func (c *Ctx) BindURI(obj any) error {
-	err := binding.URI.BindURI(c.params, obj)
+	err := binding.URI.BindURI(c.params.Map(), obj)
 	if err != nil {
 		return err
 	}
@@ -1372,6 +1525,10 @@ func (c *Ctx) BindQuery(obj any) error {
 }
 
 func (c *Ctx) BindForm(obj any) error {
+	if err := c.rewindBody(); err != nil {
+		return err
+	}
+
 	err := binding.Form.Bind(c.r, obj)
 	if err != nil {
 		return err
@@ -1384,6 +1541,7 @@ func (c *Ctx) GetRequest() *http.Request {
 }
 
 func (c *Ctx) Render(code int, r render.Render) error {
+	r.WritingContentType(c.w)
 	c.w.WriteHeader(code)
 	return r.Render(c.w)
 }
@@ -1399,3 +1557,56 @@ func (c *Ctx) Path() string {
 	}
 	return c.r.URL.Path
 }
+
+// RoutePattern returns the pattern of the matched route, e.g. "/users/:id".
+// It is empty when no route matched the request.
+func (c *Ctx) RoutePattern() string {
+	return c.routerPath
+}
+
+// ClientIP returns the IP address of the client. The X-Forwarded-For and
+// X-Real-IP headers are only read when the request comes from one of the
+// proxies set with Mux.SetTrustedProxies, the remote address of the
+// connection is returned otherwise. X-Forwarded-For is read from the right,
+// skipping the trusted proxies, since the client controls its left part.
+func (c *Ctx) ClientIP() string {
+	remote, _, err := net.SplitHostPort(c.r.RemoteAddr)
+	if err != nil {
+		remote = c.r.RemoteAddr
+	}
+
+	if c.mux == nil || !c.mux.trustedProxy(remote) {
+		return remote
+	}
+
+	if forwarded := c.r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if ip != "" && !c.mux.trustedProxy(ip) {
+				return ip
+			}
+		}
+	}
+
+	if ip := strings.TrimSpace(c.r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+
+	return remote
+}
+
+// HandleError passes err to the error handler of the Mux right away, so
+// the error response is written before the middleware returns. Middlewares
+// that inspect the final response, like Logger, use it instead of
+// returning the error.
+func (c *Ctx) HandleError(err error) {
+	if c.mux == nil {
+		if err != nil {
+			DefaultErrorHandler(c, err)
+		}
+		return
+	}
+
+	c.mux.handleError(c, err)
+}
@@ -0,0 +1,135 @@
+package tree
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Decoder creates a reader decompressing r.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"x-gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		// the deflate content coding is the zlib format (RFC 9110)
+		"deflate": zlib.NewReader,
+	}
+)
+
+// RegisterDecoder makes a content coding, e.g. "br" or "zstd", available
+// to Decompress. gzip and deflate are registered by default.
+func RegisterDecoder(name string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	decoders[strings.ToLower(name)] = decoder
+}
+
+func lookupDecoder(name string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	decoder, ok := decoders[strings.ToLower(name)]
+	return decoder, ok
+}
+
+type DecompressConfig struct {
+	// MaxSize caps the size of the decompressed body, 10 MB by default.
+	// Reading past it fails with an *http.MaxBytesError, so a small
+	// compressed body can't expand into gigabytes (zip bomb).
+	MaxSize int64
+	Skipper func(*Ctx) bool
+}
+
+// Decompress decodes request bodies sent with a Content-Encoding, so
+// Ctx.Body, the Bind methods and ParseForm read the original content.
+// Bodies in an unknown encoding are rejected with 415 Unsupported Media
+// Type and malformed ones with 400 Bad Request.
+func Decompress(config ...DecompressConfig) func(*Ctx) error {
+	cfg := UseDefaultDecompress()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20 // 10 MB
+	}
+
+	return func(c *Ctx) error {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+
+		encoding := strings.TrimSpace(c.r.Header.Get("Content-Encoding"))
+		if encoding == "" || strings.EqualFold(encoding, "identity") || c.r.Body == nil || c.r.Body == http.NoBody {
+			return c.Next()
+		}
+
+		decoder, ok := lookupDecoder(encoding)
+		if !ok {
+			return NewHTTPError(UnsupportedMediaType, "unsupported content encoding: "+encoding)
+		}
+
+		// an earlier middleware already read the body, decode the cached
+		// copy and let the Ctx cache the decoded body instead
+		if c.body.cached {
+			if err := c.rewindBody(); err != nil {
+				return err
+			}
+			raw := c.body
+			c.body = bodyCache{}
+			defer raw.reset()
+		}
+
+		body, err := decoder(c.r.Body)
+		if err != nil {
+			return NewHTTPError(BadRequest, "invalid "+encoding+" body")
+		}
+		defer body.Close()
+
+		c.r.Body = http.MaxBytesReader(c.w, &decodeErrReader{body, encoding}, cfg.MaxSize)
+		c.r.ContentLength = -1
+		c.r.Header.Del("Content-Encoding")
+		c.r.Header.Del("Content-Length")
+
+		return c.Next()
+	}
+}
+
+// decodeErrReader reports malformed compressed data as a 400 HTTPError.
+type decodeErrReader struct {
+	io.ReadCloser
+	encoding string
+}
+
+func (r *decodeErrReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	// the compressed body went over the limit of the route
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return n, err
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, NewHTTPError(BadRequest, "invalid "+r.encoding+" body", err.Error())
+	}
+	return n, err
+}
+
+func UseDefaultDecompress() DecompressConfig {
+	return DecompressConfig{
+		MaxSize: 10 << 20, // 10 MB
+	}
+}
@@ -0,0 +1,170 @@
+package tree
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipBody(t *testing.T, data string) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestDecompress(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	mux := InitMux()
+	mux.USE("/", Decompress())
+	mux.POST("/users", func(c *Ctx) error {
+		var u user
+		if err := c.BindJSON(&u); err != nil {
+			return err
+		}
+		return c.SendString(u.Name, OK)
+	})
+
+	var deflated bytes.Buffer
+	zw := zlib.NewWriter(&deflated)
+	zw.Write([]byte(`{"name":"deflate"}`))
+	zw.Close()
+
+	tests := []struct {
+		encoding string
+		body     *bytes.Buffer
+		expected string
+	}{
+		{"gzip", gzipBody(t, `{"name":"gzip"}`), "gzip"},
+		{"deflate", &deflated, "deflate"},
+		{"", bytes.NewBufferString(`{"name":"plain"}`), "plain"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/users", tt.body)
+		req.Header.Set("Content-Type", "application/json")
+		if tt.encoding != "" {
+			req.Header.Set("Content-Encoding", tt.encoding)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != OK || w.Body.String() != tt.expected {
+			t.Errorf("%q: expected 200 %q, got %d %q", tt.encoding, tt.expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestDecompress_BodyReadBefore(t *testing.T) {
+	for _, maxMemory := range []int64{10 << 20, 8} {
+		var raw []byte
+
+		mux := InitMux()
+		mux.USE("/", func(c *Ctx) error {
+			// a small limit spills the compressed body to a file
+			c.SetMaxMemParsed(maxMemory)
+
+			body, err := c.Body()
+			if err != nil {
+				return err
+			}
+			raw = body
+			return c.Next()
+		})
+		mux.USE("/", Decompress())
+		mux.POST("/echo", func(c *Ctx) error {
+			body, err := c.Body()
+			if err != nil {
+				return err
+			}
+			return c.SendString(string(body), OK)
+		})
+
+		content := strings.Repeat("tree ", 100)
+		req := httptest.NewRequest("POST", "/echo", gzipBody(t, content))
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != OK || w.Body.String() != content {
+			t.Errorf("maxMemory=%d: expected the decompressed body, got %d (%d bytes)", maxMemory, w.Code, w.Body.Len())
+		}
+		if len(raw) == 0 || string(raw) == content {
+			t.Errorf("maxMemory=%d: expected the middleware to read the compressed body", maxMemory)
+		}
+	}
+}
+
+func TestDecompress_Errors(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", Decompress())
+	mux.POST("/echo", func(c *Ctx) error {
+		body, err := c.Body()
+		if err != nil {
+			return err
+		}
+		return c.SendString(string(body), OK)
+	})
+
+	tests := []struct {
+		encoding string
+		body     string
+		code     int
+	}{
+		{"br", "whatever", UnsupportedMediaType},
+		{"gzip", "not gzip at all", BadRequest},
+		{"gzip", gzipBody(t, "truncated body").String()[:20], BadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/echo", strings.NewReader(tt.body))
+		req.Header.Set("Content-Encoding", tt.encoding)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s %q: expected %d, got %d", tt.encoding, tt.body, tt.code, w.Code)
+		}
+	}
+}
+
+func TestDecompress_MaxSize(t *testing.T) {
+	var readErr error
+
+	mux := InitMux()
+	mux.USE("/", Decompress(DecompressConfig{MaxSize: 1024}))
+	mux.POST("/upload", func(c *Ctx) error {
+		_, readErr = c.Body()
+		return c.Status(NoContent)
+	})
+
+	// 1 MB of zeros compresses to about 1 KB
+	body := gzipBody(t, strings.Repeat("\x00", 1<<20))
+	if body.Len() > 4096 {
+		t.Fatalf("Expected a highly compressed body, got %d bytes", body.Len())
+	}
+
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Encoding", "gzip")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	var maxErr *http.MaxBytesError
+	if !errors.As(readErr, &maxErr) || maxErr.Limit != 1024 {
+		t.Errorf("Expected an *http.MaxBytesError with limit 1024, got %v", readErr)
+	}
+}
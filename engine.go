@@ -1,16 +1,58 @@
 package tree
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 type ServerConfig struct {
 	Port                string
 	AutomaticMiddleware bool
+
+	// Timeouts of the underlying http.Server, zero means no timeout.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxHeaderBytes limits the size of the request headers, zero uses
+	// http.DefaultMaxHeaderBytes.
+	MaxHeaderBytes int
+
+	// MaxBodyBytes limits the size of the request bodies, zero keeps the
+	// limit set with Mux.SetMaxBodyBytes, none by default. Routes can
+	// override it with Route.MaxBodyBytes.
+	MaxBodyBytes int64
+
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ShutdownTimeout is how long in-flight requests are given to finish
+	// once the server is asked to stop. The default is 10 seconds.
+	ShutdownTimeout time.Duration
 }
 
-func (r *Mux) StartExecuting(sc ...ServerConfig) {
+// StartExecuting starts the server and blocks until it fails or the
+// process receives SIGINT or SIGTERM, in which case in-flight requests are
+// drained before returning.
+func (r *Mux) StartExecuting(sc ...ServerConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return r.StartExecutingContext(ctx, sc...)
+}
+
+// StartExecutingContext starts the server and blocks until it fails or ctx
+// is done. When ctx is done the server stops accepting connections and
+// waits up to ServerConfig.ShutdownTimeout for in-flight requests.
+func (r *Mux) StartExecutingContext(ctx context.Context, sc ...ServerConfig) error {
 	var cfg ServerConfig
 	if len(sc) > 0 {
 		cfg = sc[0]
@@ -20,12 +62,65 @@ func (r *Mux) StartExecuting(sc ...ServerConfig) {
 		cfg.Port = ":8080"
 	}
 
-	log.Println("Starting server on port", cfg.Port)
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return errors.New("both TLSCertFile and TLSKeyFile must be set to enable TLS")
+	}
+
 	r.setMiddlewareAutomatically(cfg.AutomaticMiddleware)
-	r.trees = r.buildTrees()
+	if cfg.MaxBodyBytes != 0 {
+		r.SetMaxBodyBytes(cfg.MaxBodyBytes)
+	}
+	r.initTrees()
+
+	srv := r.newServer(cfg)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("Starting server on port", cfg.Port)
+		if cfg.TLSCertFile != "" {
+			serveErr <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down server, waiting for in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
 
-	err := http.ListenAndServe(cfg.Port, r)
-	if err != nil {
-		log.Printf("[ERROR] Failed to start server. Error %s", err)
+func (r *Mux) newServer(cfg ServerConfig) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Port,
+		Handler:           r,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 }
@@ -0,0 +1,175 @@
+package tree
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+func waitForServer(t *testing.T, addr string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server on %s did not start", addr)
+}
+
+func TestStartExecutingContext_GracefulShutdown(t *testing.T) {
+	mux := InitMux()
+
+	started := make(chan struct{})
+	mux.GET("/slow", func(c *Ctx) error {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		return c.SendString("done", 200)
+	})
+
+	addr := freePort(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- mux.StartExecutingContext(ctx, ServerConfig{
+			Port:              addr,
+			ReadHeaderTimeout: time.Second,
+			ShutdownTimeout:   2 * time.Second,
+		})
+	}()
+	waitForServer(t, addr)
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-response
+	if res.err != nil || res.body != "done" {
+		t.Errorf("In-flight request was not drained: body=%q err=%v", res.body, res.err)
+	}
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Errorf("Expected nil error after graceful shutdown, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestStartExecutingContext_KeepsMaxBodyBytes(t *testing.T) {
+	mux := InitMux()
+	mux.SetMaxBodyBytes(4)
+	mux.POST("/echo", func(c *Ctx) error {
+		body, err := c.Body()
+		if err != nil {
+			return err
+		}
+		return c.SendString(string(body), OK)
+	})
+
+	addr := freePort(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- mux.StartExecutingContext(ctx, ServerConfig{Port: addr})
+	}()
+	defer func() {
+		cancel()
+		<-serverErr
+	}()
+	waitForServer(t, addr)
+
+	resp, err := http.Post("http://"+addr+"/echo", "text/plain", strings.NewReader("0123456789"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != RequestEntityTooLarge {
+		t.Errorf("Expected the limit of the Mux to be kept, got %d", resp.StatusCode)
+	}
+}
+
+func TestStartExecutingContext_Errors(t *testing.T) {
+	mux := InitMux()
+
+	err := mux.StartExecutingContext(context.Background(), ServerConfig{
+		Port:        freePort(t),
+		TLSCertFile: "cert.pem",
+	})
+	if err == nil {
+		t.Error("Expected error when only TLSCertFile is set")
+	}
+
+	err = mux.StartExecutingContext(context.Background(), ServerConfig{
+		Port:        freePort(t),
+		TLSCertFile: "missing-cert.pem",
+		TLSKeyFile:  "missing-key.pem",
+	})
+	if err == nil {
+		t.Error("Expected error for missing TLS files")
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	mux := InitMux()
+
+	srv := mux.newServer(ServerConfig{
+		Port:              ":9000",
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+		MaxHeaderBytes:    1 << 10,
+	})
+
+	if srv.Addr != ":9000" || srv.Handler != mux {
+		t.Error("Server address or handler not set")
+	}
+	if srv.ReadTimeout != time.Second || srv.ReadHeaderTimeout != 2*time.Second ||
+		srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second {
+		t.Error("Server timeouts not applied")
+	}
+	if srv.MaxHeaderBytes != 1<<10 {
+		t.Errorf("Expected MaxHeaderBytes 1024, got %d", srv.MaxHeaderBytes)
+	}
+}
@@ -1,6 +1,14 @@
 package tree
 
-import "errors"
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/catalinfl/tree-framework/render"
+)
 
 var (
 	ErrInvalidJSONType          = errors.New("invalid json type")
@@ -18,3 +26,126 @@ var (
 	ErrHeaderNotFound           = errors.New("header not found")
 	ErrOneOrMoreHeadersNotFound = errors.New("one or more headers not found")
 )
+
+// HTTPError is an error carrying the HTTP status code and message that
+// should be sent to the client. Handlers and middlewares can return it and
+// the Mux error handler renders it.
+type HTTPError struct {
+	Code    int    `json:"code" yaml:"code" toml:"code"`
+	Message string `json:"message" yaml:"message" toml:"message"`
+	Details any    `json:"details,omitempty" yaml:"details,omitempty" toml:"details,omitempty"`
+}
+
+// NewHTTPError creates an HTTPError. An empty message is replaced with the
+// standard status text of the code.
+func NewHTTPError(code int, message string, details ...any) *HTTPError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+
+	e := &HTTPError{
+		Code:    code,
+		Message: message,
+	}
+	if len(details) == 1 {
+		e.Details = details[0]
+	} else if len(details) > 1 {
+		e.Details = details
+	}
+
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// ErrorHandler handles errors returned by route handlers and middlewares.
+type ErrorHandler func(*Ctx, error)
+
+// SetErrorHandler replaces the error handler of the Mux. Passing nil
+// restores DefaultErrorHandler.
+func (r *Mux) SetErrorHandler(h ErrorHandler) {
+	if h == nil {
+		h = DefaultErrorHandler
+	}
+	r.errorHandler = h
+}
+
+func (r *Mux) handleError(c *Ctx, err error) {
+	if err == nil {
+		return
+	}
+
+	if r.errorHandler == nil {
+		DefaultErrorHandler(c, err)
+		return
+	}
+	r.errorHandler(c, err)
+}
+
+type xmlError struct {
+	XMLName   xml.Name `xml:"error"`
+	Code      int      `xml:"code"`
+	Message   string   `xml:"message"`
+	Details   string   `xml:"details,omitempty"`
+	RequestID string   `xml:"request_id,omitempty"`
+}
+
+// DefaultErrorHandler renders the error as an {"error": {...}} envelope in
+// the format negotiated through the Accept header (JSON by default).
+// Body size limit errors (*http.MaxBytesError) are reported as 413, other
+// errors which are not an *HTTPError as 500 Internal Server Error. Their
+// message is logged instead of being sent to the client. The ID set by the
+// RequestID middleware is added next to the error. Nothing is rendered
+// when the handler already started writing the response.
+func DefaultErrorHandler(c *Ctx, err error) {
+	var httpErr *HTTPError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &httpErr):
+	case errors.As(err, &maxBytesErr):
+		httpErr = NewHTTPError(RequestEntityTooLarge, "")
+	default:
+		if requestID := c.RequestID(); requestID != "" {
+			log.Printf("[ERROR] %s %s (request %s): %v\n", c.GetMethod(), c.Path(), requestID, err)
+		} else {
+			log.Printf("[ERROR] %s %s: %v\n", c.GetMethod(), c.Path(), err)
+		}
+		httpErr = NewHTTPError(InternalError, "")
+	}
+
+	if c.Written() {
+		return
+	}
+
+	envelope := J{"error": httpErr}
+	requestID := c.RequestID()
+	if requestID != "" {
+		envelope["request_id"] = requestID
+	}
+
+	switch c.Accept("application/json", "application/xml", "text/xml", "application/x-yaml", "application/yaml", "application/toml", "text/plain") {
+	case "application/xml", "text/xml":
+		xmlErr := &xmlError{Code: httpErr.Code, Message: httpErr.Message, RequestID: requestID}
+		if httpErr.Details != nil {
+			xmlErr.Details = fmt.Sprint(httpErr.Details)
+		}
+		c.Render(httpErr.Code, &render.XML{Data: xmlErr})
+	case "application/x-yaml", "application/yaml":
+		c.Render(httpErr.Code, &render.YAML{Data: envelope})
+	case "application/toml":
+		c.Render(httpErr.Code, &render.TOML{Data: envelope})
+	case "text/plain":
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		c.w.WriteHeader(httpErr.Code)
+		if requestID != "" {
+			render.WriteString(c.w, "%d %s (request %s)", []any{httpErr.Code, httpErr.Message, requestID})
+		} else {
+			render.WriteString(c.w, "%d %s", []any{httpErr.Code, httpErr.Message})
+		}
+	default:
+		c.SetHeader("Content-Type", "application/json; charset=utf-8")
+		c.RenderJSON(httpErr.Code, render.JSON{Data: envelope})
+	}
+}
@@ -0,0 +1,145 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDefaultErrorHandler_HTTPError(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/users/:id", func(c *Ctx) error {
+		return NewHTTPError(NotFound, "user not found", J{"id": "42"})
+	})
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != NotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Expected JSON content type, got '%s'", ct)
+	}
+
+	var body struct {
+		Error HTTPError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if body.Error.Code != NotFound || body.Error.Message != "user not found" {
+		t.Errorf("Unexpected error envelope: %+v", body.Error)
+	}
+	if details, ok := body.Error.Details.(map[string]any); !ok || details["id"] != "42" {
+		t.Errorf("Unexpected error details: %v", body.Error.Details)
+	}
+}
+
+func TestDefaultErrorHandler_PlainError(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	mux := InitMux()
+
+	mux.GET("/fail", func(c *Ctx) error {
+		return errors.New("database password is hunter2")
+	})
+
+	req := httptest.NewRequest("GET", "/fail", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != InternalError {
+		t.Errorf("Expected status 500, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "hunter2") {
+		t.Error("Internal error message leaked to the client")
+	}
+	if !strings.Contains(logged.String(), "[ERROR] GET /fail: database password is hunter2") {
+		t.Errorf("Expected the error to be logged, got %q", logged.String())
+	}
+}
+
+func TestDefaultErrorHandler_Negotiation(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/teapot", func(c *Ctx) error {
+		return NewHTTPError(Teapot, "")
+	})
+
+	tests := []struct {
+		accept      string
+		contentType string
+		contains    string
+	}{
+		{"application/xml", "application/xml; charset=utf-8", "<code>418</code>"},
+		{"text/plain", "text/plain; charset=utf-8", "418 I'm a teapot"},
+		{"application/x-yaml", "application/x-yaml; charset=utf-8", "code: 418"},
+		{"", "application/json; charset=utf-8", `"code":418`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/teapot", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != Teapot {
+			t.Errorf("Accept %q: expected status 418, got %d", tt.accept, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("Accept %q: expected content type %q, got %q", tt.accept, tt.contentType, ct)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if !strings.Contains(string(body), tt.contains) {
+			t.Errorf("Accept %q: expected body to contain %q, got %q", tt.accept, tt.contains, string(body))
+		}
+	}
+}
+
+func TestMux_SetErrorHandler(t *testing.T) {
+	mux := InitMux()
+	mux.setMiddlewareAutomatically(true)
+
+	var handled error
+	mux.SetErrorHandler(func(c *Ctx, err error) {
+		handled = err
+		c.SendString("custom", 503)
+	})
+
+	mux.USE("/", func(c *Ctx) error {
+		return NewHTTPError(Unauthorized, "")
+	})
+	mux.GET("/secret", func(c *Ctx) error {
+		return c.SendString("secret", 200)
+	})
+
+	req := httptest.NewRequest("GET", "/secret", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	var httpErr *HTTPError
+	if !errors.As(handled, &httpErr) || httpErr.Code != Unauthorized {
+		t.Errorf("Expected middleware HTTPError to reach the error handler, got %v", handled)
+	}
+	if w.Result().StatusCode != 503 {
+		t.Errorf("Expected status 503, got %d", w.Result().StatusCode)
+	}
+}
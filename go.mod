@@ -3,7 +3,7 @@ module github.com/catalinfl/tree-framework
 go 1.23
 
 require (
-	github.com/BurntSushi/toml v1.5.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/BurntSushi/toml v1.5.0
+	github.com/google/uuid v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,120 @@
+package tree
+
+import "strings"
+
+// Group is a set of routes sharing a path prefix and a middleware chain.
+// Routes registered through a Group are stored on the parent Mux, so the
+// combined prefix and middlewares end up in the trees built by buildTrees.
+type Group struct {
+	mux         *Mux
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group creates a route group under prefix. The given middlewares run, in
+// order, before the handler of every route registered through the group.
+func (r *Mux) Group(prefix string, middlewares ...MiddlewareFunc) *Group {
+	return &Group{
+		mux:         r,
+		prefix:      joinPaths("", prefix),
+		middlewares: toMiddlewares(prefix, middlewares),
+	}
+}
+
+// Group creates a nested group. The nested group inherits the prefix and
+// middlewares of its parent, followed by its own.
+func (g *Group) Group(prefix string, middlewares ...MiddlewareFunc) *Group {
+	fullPrefix := joinPaths(g.prefix, prefix)
+
+	chain := make([]Middleware, 0, len(g.middlewares)+len(middlewares))
+	chain = append(chain, g.middlewares...)
+	chain = append(chain, toMiddlewares(fullPrefix, middlewares)...)
+
+	return &Group{
+		mux:         g.mux,
+		prefix:      fullPrefix,
+		middlewares: chain,
+	}
+}
+
+// Prefix returns the full path prefix of the group.
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+func (g *Group) GET(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, GET, t, middlewares)
+}
+
+func (g *Group) POST(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, POST, t, middlewares)
+}
+
+func (g *Group) PUT(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, PUT, t, middlewares)
+}
+
+func (g *Group) DELETE(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, DELETE, t, middlewares)
+}
+
+func (g *Group) PATCH(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, PATCH, t, middlewares)
+}
+
+func (g *Group) HEAD(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, HEAD, t, middlewares)
+}
+
+func (g *Group) OPTIONS(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.handle(url, OPTIONS, t, middlewares)
+}
+
+// USE registers a path-prefix middleware relative to the group prefix.
+// An empty url applies the middleware to the whole group.
+func (g *Group) USE(url string, t CtxFunc) {
+	g.mux.USE(joinPaths(g.prefix, url), t)
+}
+
+func (g *Group) handle(url string, method Method, t CtxFunc, middlewares []MiddlewareFunc) *Route {
+	path := joinPaths(g.prefix, url)
+
+	chain := make([]Middleware, 0, len(g.middlewares)+len(middlewares))
+	chain = append(chain, g.middlewares...)
+	chain = append(chain, toMiddlewares(path, middlewares)...)
+
+	return g.mux.addRoute(path, method, t, chain)
+}
+
+func toMiddlewares(path string, handlers []MiddlewareFunc) []Middleware {
+	middlewares := make([]Middleware, 0, len(handlers))
+	for _, handler := range handlers {
+		if handler == nil {
+			continue
+		}
+		middlewares = append(middlewares, Middleware{
+			Path:    path,
+			Handler: handler,
+		})
+	}
+
+	return middlewares
+}
+
+// joinPaths joins a group prefix with a route path, making sure there is
+// exactly one slash between them and no trailing slash (except for "/").
+func joinPaths(prefix, path string) string {
+	prefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+	path = strings.Trim(strings.TrimSpace(path), "/")
+
+	joined := prefix
+	if path != "" {
+		joined += "/" + path
+	}
+
+	if !strings.HasPrefix(joined, "/") {
+		joined = "/" + joined
+	}
+
+	return joined
+}
@@ -0,0 +1,146 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type LogFormat int
+
+const (
+	// LogFormatText logs key=value pairs with slog.TextHandler.
+	LogFormatText LogFormat = iota
+	// LogFormatJSON logs JSON objects with slog.JSONHandler.
+	LogFormatJSON
+	// LogFormatCombined logs lines in the Apache combined log format.
+	LogFormatCombined
+)
+
+type LoggerConfig struct {
+	// Format of the access log, LogFormatText by default.
+	Format LogFormat
+	// Output receives the log lines, os.Stdout by default.
+	Output io.Writer
+	// Logger is used instead of Output for the text and JSON formats, so
+	// requests can be logged with an application wide slog handler.
+	Logger *slog.Logger
+	// RequestIDHeader is read from the response, then from the request,
+	// to log the request ID when the RequestID middleware didn't run.
+	// DefaultRequestIDHeader by default.
+	RequestIDHeader string
+	Skipper         func(*Ctx) bool
+}
+
+// Logger logs every request once it has been served, with its method,
+// route pattern, path, status, latency, response size, client IP and
+// request ID. Requests with a 5xx status are logged at error level and
+// 4xx ones at warning level.
+//
+// Errors returned further down the chain are handled by Logger through
+// Ctx.HandleError, so the logged status is the one sent to the client.
+func Logger(config ...LoggerConfig) func(*Ctx) error {
+	cfg := UseDefaultLogger()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = DefaultRequestIDHeader
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		switch cfg.Format {
+		case LogFormatJSON:
+			logger = slog.New(slog.NewJSONHandler(cfg.Output, nil))
+		default:
+			logger = slog.New(slog.NewTextHandler(cfg.Output, nil))
+		}
+	}
+
+	var mu sync.Mutex
+
+	return func(c *Ctx) error {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+
+		start := time.Now()
+
+		if err := c.Next(); err != nil {
+			c.HandleError(err)
+		}
+
+		latency := time.Since(start)
+		status := c.StatusCode()
+
+		requestID := c.RequestID()
+		if requestID == "" {
+			requestID = c.HeaderSent().Get(cfg.RequestIDHeader)
+		}
+		if requestID == "" {
+			requestID = c.Header().Get(cfg.RequestIDHeader)
+		}
+
+		if cfg.Format == LogFormatCombined {
+			mu.Lock()
+			defer mu.Unlock()
+
+			fmt.Fprintf(cfg.Output, "%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\"\n",
+				c.ClientIP(),
+				start.Format("02/Jan/2006:15:04:05 -0700"),
+				c.GetMethod(),
+				c.r.URL.RequestURI(),
+				c.r.Proto,
+				status,
+				c.BytesWritten(),
+				orDash(c.r.Referer()),
+				orDash(c.r.UserAgent()),
+			)
+			return nil
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= InternalError:
+			level = slog.LevelError
+		case status >= BadRequest:
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(c.r.Context(), level, "request",
+			slog.String("method", c.GetMethod()),
+			slog.String("route", c.RoutePattern()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Duration("latency", latency),
+			slog.Int64("bytes", c.BytesWritten()),
+			slog.String("ip", c.ClientIP()),
+			slog.String("request_id", requestID),
+		)
+
+		return nil
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func UseDefaultLogger() LoggerConfig {
+	return LoggerConfig{
+		Format:          LogFormatText,
+		Output:          os.Stdout,
+		RequestIDHeader: DefaultRequestIDHeader,
+	}
+}
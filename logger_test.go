@@ -0,0 +1,163 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogger_JSON(t *testing.T) {
+	var out bytes.Buffer
+
+	mux := InitMux()
+	mux.USE("/", Logger(LoggerConfig{Format: LogFormatJSON, Output: &out}))
+	mux.GET("/users/:id", func(c *Ctx) error {
+		return c.SendString("user", OK)
+	})
+	mux.GET("/missing", func(c *Ctx) error {
+		return NewHTTPError(NotFound, "no such user")
+	})
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	req.RemoteAddr = "10.0.0.1:5000"
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", out.String(), err)
+	}
+
+	expected := map[string]any{
+		"level":      "INFO",
+		"msg":        "request",
+		"method":     "GET",
+		"route":      "/users/:id",
+		"path":       "/users/42",
+		"status":     float64(OK),
+		"bytes":      float64(4),
+		"ip":         "10.0.0.1",
+		"request_id": "abc-123",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, entry[k])
+		}
+	}
+	if _, ok := entry["latency"]; !ok {
+		t.Error("Expected latency to be logged")
+	}
+
+	out.Reset()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+
+	if w.Code != NotFound {
+		t.Errorf("Expected the error handler to answer 404, got %d", w.Code)
+	}
+	entry = nil
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", out.String(), err)
+	}
+	if entry["status"] != float64(NotFound) || entry["level"] != "WARN" {
+		t.Errorf("Expected a WARN entry with status 404, got %v", entry)
+	}
+}
+
+func TestLogger_Combined(t *testing.T) {
+	var out bytes.Buffer
+
+	mux := InitMux()
+	mux.USE("/", Logger(LoggerConfig{Format: LogFormatCombined, Output: &out}))
+	mux.GET("/users", func(c *Ctx) error {
+		return c.SendString("users", OK)
+	})
+
+	req := httptest.NewRequest("GET", "/users?page=2", nil)
+	req.RemoteAddr = "192.168.1.10:1234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := out.String()
+	if !strings.HasPrefix(line, "192.168.1.10 - - [") {
+		t.Errorf("Unexpected combined log prefix: %q", line)
+	}
+	if !strings.HasSuffix(line, "] \"GET /users?page=2 HTTP/1.1\" 200 5 \"-\" \"curl/8.0\"\n") {
+		t.Errorf("Unexpected combined log line: %q", line)
+	}
+}
+
+func TestLogger_Skipper(t *testing.T) {
+	var out bytes.Buffer
+
+	mux := InitMux()
+	mux.USE("/", Logger(LoggerConfig{
+		Output: &out,
+		Skipper: func(c *Ctx) bool {
+			return c.Path() == "/health"
+		},
+	}))
+	mux.GET("/health", func(c *Ctx) error {
+		return c.SendString("ok", OK)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+
+	if w.Body.String() != "ok" {
+		t.Errorf("Expected the handler to run, got %q", w.Body.String())
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no log for a skipped request, got %q", out.String())
+	}
+}
+
+func TestCtx_ClientIP(t *testing.T) {
+	mux := InitMux()
+	if err := mux.SetTrustedProxies("10.0.0.0/8", "::1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		headers  map[string]string
+		remote   string
+		expected string
+	}{
+		{nil, "10.0.0.1:5000", "10.0.0.1"},
+		{nil, "[::1]:5000", "::1"},
+		{map[string]string{"X-Real-IP": "1.2.3.4"}, "10.0.0.1:5000", "1.2.3.4"},
+		{map[string]string{"X-Forwarded-For": "5.6.7.8, 10.0.0.2", "X-Real-IP": "1.2.3.4"}, "10.0.0.1:5000", "5.6.7.8"},
+		// the client controls the left part of X-Forwarded-For
+		{map[string]string{"X-Forwarded-For": "9.9.9.9, 5.6.7.8"}, "[::1]:5000", "5.6.7.8"},
+		// headers from untrusted peers are ignored
+		{map[string]string{"X-Forwarded-For": "5.6.7.8", "X-Real-IP": "1.2.3.4"}, "192.168.1.1:5000", "192.168.1.1"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+
+		c := NewCtx(httptest.NewRecorder(), req, "/", nil, nil, false)
+		c.mux = mux
+		if ip := c.ClientIP(); ip != tt.expected {
+			t.Errorf("%v %s: expected %s, got %s", tt.headers, tt.remote, tt.expected, ip)
+		}
+	}
+
+	// without trusted proxies the headers are never read
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "5.6.7.8")
+	if ip := NewCtx(httptest.NewRecorder(), req, "/", nil, nil, false).ClientIP(); ip != "10.0.0.1" {
+		t.Errorf("Expected the remote address without trusted proxies, got %s", ip)
+	}
+
+	if err := mux.SetTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("Expected an error for an invalid proxy range")
+	}
+}
@@ -1,33 +1,39 @@
 package tree
 
-func (r *Mux) GET(url string, t CtxFunc) {
-	r.addMutationRoute(url, GET, t)
+func (r *Mux) GET(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, GET, t, middlewares...)
 }
 
-func (r *Mux) POST(url string, t CtxFunc) {
-	r.addMutationRoute(url, POST, t)
+func (r *Mux) POST(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, POST, t, middlewares...)
 }
 
-func (r *Mux) PUT(url string, t CtxFunc) {
-	r.addMutationRoute(url, PUT, t)
+func (r *Mux) PUT(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, PUT, t, middlewares...)
 }
 
-func (r *Mux) DELETE(url string, t CtxFunc) {
-	r.addMutationRoute(url, DELETE, t)
+func (r *Mux) DELETE(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, DELETE, t, middlewares...)
 }
 
-func (r *Mux) PATCH(url string, t CtxFunc) {
-	r.addMutationRoute(url, PATCH, t)
+func (r *Mux) PATCH(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, PATCH, t, middlewares...)
 }
 
-func (r *Mux) HEAD(url string, t CtxFunc) {
-	r.addMutationRoute(url, HEAD, t)
+func (r *Mux) HEAD(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, HEAD, t, middlewares...)
 }
 
-func (r *Mux) OPTIONS(url string, t CtxFunc) {
-	r.addMutationRoute(url, OPTIONS, t)
+func (r *Mux) OPTIONS(url string, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addMutationRoute(url, OPTIONS, t, middlewares...)
 }
 
+// USE registers a middleware for the requests whose path is url or starts
+// with url followed by "/". The middlewares of a route are resolved once,
+// from its pattern: a url within the static part of the pattern, e.g.
+// "/users" for "/users/:id", always runs for the route, while a url
+// reaching into its parameters, e.g. "/users/admin", is checked against the
+// request path and only runs for the requests it matches.
 func (r *Mux) USE(url string, t CtxFunc) {
 
 	if url == "" {
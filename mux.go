@@ -1,10 +1,13 @@
 package tree
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Method int
@@ -37,10 +40,25 @@ func (m Method) String() string {
 	return methodToString[m]
 }
 
+// parseMethod returns the routable Method matching an HTTP request method.
+func parseMethod(method string) (Method, bool) {
+	for m := GET; m <= OPTIONS; m++ {
+		if methodToString[m] == method {
+			return m, true
+		}
+	}
+
+	return 0, false
+}
+
 type Route struct {
-	h          *http.HandlerFunc
-	CtxHandler CtxFunc
-	path       string
+	CtxHandler   CtxFunc
+	path         string
+	name         string
+	segments     []urlSegment
+	middlewares  []Middleware
+	maxBodyBytes int64
+	mux          *Mux
 }
 
 type MiddlewareFunc func(*Ctx) error
@@ -48,48 +66,69 @@ type MiddlewareFunc func(*Ctx) error
 type Middleware struct {
 	Path    string
 	Handler MiddlewareFunc
+	// checkPath makes Ctx.Next match Path against the request path before
+	// running the middleware, see Mux.middlewareChain.
+	checkPath bool
 }
 
 type Mux struct {
-	handlers    map[Method]*[]Route
-	middlewares []Middleware
-	handlerMap  map[*http.HandlerFunc]CtxFunc
-	automatic   bool
-	trees       map[Method]*Tree
+	handlers         map[Method]*[]*Route
+	namedRoutes      map[string]*Route
+	middlewares      []Middleware
+	automatic        bool
+	trees            map[Method]*Tree
+	treesOnce        sync.Once
+	errorHandler     ErrorHandler
+	notFound         CtxFunc
+	methodNotAllowed CtxFunc
+	maxBodyBytes     int64
+	trustedProxies   []netip.Prefix
+	pool             sync.Pool
 }
 
 func InitMux() *Mux {
 	return &Mux{
-		handlers:    make(map[Method]*[]Route),
-		middlewares: make([]Middleware, 0),
-		handlerMap:  make(map[*http.HandlerFunc]CtxFunc),
-		automatic:   false,
-		trees:       nil,
+		handlers:     make(map[Method]*[]*Route),
+		namedRoutes:  make(map[string]*Route),
+		middlewares:  make([]Middleware, 0),
+		automatic:    false,
+		trees:        nil,
+		errorHandler: DefaultErrorHandler,
 	}
 }
 
-func (r *Mux) addMutationRoute(url string, method Method, t CtxFunc) {
-
-	wrappedHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		t(NewCtx(w, req, url, r.middlewares, t, r.automatic))
-	})
+// addMutationRoute registers a route with its own middlewares, which run
+// after the USE middlewares matching the route.
+func (r *Mux) addMutationRoute(url string, method Method, t CtxFunc, middlewares ...MiddlewareFunc) *Route {
+	return r.addRoute(url, method, t, toMiddlewares(url, middlewares))
+}
 
-	r.handlerMap[&wrappedHandler] = t
+// addRoute registers a route together with the middlewares scoped to it
+// (the chain of the group it was declared in followed by its own ones),
+// which end up on the tree node built for the route. It panics when the
+// pattern is malformed, uses an unknown constraint or an invalid regex.
+func (r *Mux) addRoute(url string, method Method, t CtxFunc, middlewares []Middleware) *Route {
+	if _, err := cleanPattern(url); err != nil {
+		panic(fmt.Sprintf("%s %s: %v", method, url, err))
+	}
 
-	mType := Route{
-		h:          &wrappedHandler,
-		CtxHandler: t,
-		path:       url,
+	mType := &Route{
+		CtxHandler:  t,
+		path:        url,
+		middlewares: middlewares,
+		mux:         r,
 	}
 	if _, ok := r.handlers[method]; !ok {
-		r.handlers[method] = &[]Route{mType}
+		r.handlers[method] = &[]*Route{mType}
 	} else {
 		*r.handlers[method] = append(*r.handlers[method], mType)
 	}
+
+	return mType
 }
 
 func pathMatch(middlewarePath, requestPath string) bool {
-	if middlewarePath == "" {
+	if middlewarePath == "" || middlewarePath == "/" {
 		return true
 	}
 
@@ -104,25 +143,26 @@ func pathMatch(middlewarePath, requestPath string) bool {
 	return false
 }
 
-func getParams(clientPath string, serverPath string) map[string]string {
-	params := make(map[string]string)
+func getParams(clientPath string, serverPath string) Params {
+	var params Params
 
 	clientPathParts := strings.Split(clientPath, "/")
 	serverPathParts := strings.Split(serverPath, "/")
 
 	for i, part := range serverPathParts {
 		if strings.HasPrefix(part, ":") {
+			name := parseParamSegment(part).name
 			if len(clientPathParts) > i {
-				if params[part[1:]] == "" {
-					params[part[1:]] = clientPathParts[i]
-				} else if _, exists := params[part[1:]]; exists {
+				if value, _ := params.Get(name); value == "" {
+					params = append(params, Param{Key: name, Value: clientPathParts[i]})
+				} else {
 					suffix := 1
-					newParamName := part[1:]
+					newParamName := name
 					for {
 						strSuffix := strconv.Itoa(suffix)
 						newParamName = newParamName + "_" + strSuffix
-						if _, exists := params[newParamName]; !exists {
-							params[newParamName] = clientPathParts[i]
+						if _, exists := params.Get(newParamName); !exists {
+							params = append(params, Param{Key: newParamName, Value: clientPathParts[i]})
 							break
 						} else {
 							suffix++
@@ -139,68 +179,222 @@ func getParams(clientPath string, serverPath string) map[string]string {
 	return params
 }
 
+// ServeHTTP resolves the route and runs its middleware chain and handler
+// on a single Ctx, so keys, params and response state set by a middleware
+// are visible to every later middleware and to the route handler. The Ctx
+// is pooled, routing a request doesn't allocate by itself.
+//
+// HEAD requests without an explicit HEAD route are served by the GET route
+// with the body discarded, and OPTIONS requests without an explicit OPTIONS
+// route are answered with the Allow header computed from all the trees.
+// When the path is registered only for other methods the request is
+// answered by the MethodNotAllowed handler, otherwise by the NotFound one.
 func (r *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if r.trees == nil {
-		r.trees = r.buildTrees()
+	r.initTrees()
+
+	c := r.acquireCtx(w, req)
+
+	method, ok := parseMethod(req.Method)
+	if ok {
+		if node := r.lookup(method, req.URL.Path, &c.params); node != nil {
+			r.limitBody(c, node.maxBodyBytes)
+			r.serve(c, node.route, node.middlewares, node.CtxHandler)
+			return
+		}
+
+		if method == HEAD {
+			if node := r.lookup(GET, req.URL.Path, &c.params); node != nil {
+				c.w.discard = true
+				r.limitBody(c, node.maxBodyBytes)
+				r.serve(c, node.route, node.middlewares, node.CtxHandler)
+				return
+			}
+		}
 	}
 
+	middlewares := findMatchingMiddleware(r.middlewares, req.URL.Path)
+
+	if allowed := r.allowedMethods(req.URL.Path); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+		if ok && method == OPTIONS {
+			r.serve(c, "", middlewares, defaultOptions)
+			return
+		}
+
+		handler := r.methodNotAllowed
+		if handler == nil {
+			handler = defaultMethodNotAllowed
+		}
+		r.serve(c, "", middlewares, handler)
+		return
+	}
+
+	handler := r.notFound
+	if handler == nil {
+		handler = defaultNotFound
+	}
+	r.serve(c, "", middlewares, handler)
+}
+
+// lookup returns the node handling path in the tree of method, or nil.
+// The route params are appended to params only when a node is found.
+func (r *Mux) lookup(method Method, path string, params *Params) *Node {
+	if r.trees[method] == nil {
+		return nil
+	}
+
+	n := len(*params)
+	node := r.trees[method].startNode.find(path, params)
+	if node == nil || node.CtxHandler == nil {
+		*params = (*params)[:n]
+		return nil
+	}
+
+	return node
+}
+
+// middlewareChain resolves the full middleware chain of a route: the USE
+// middlewares whose path matches the route pattern followed by the route
+// scoped ones. It runs once per route in buildTrees and the result is
+// stored on the tree node, so requests don't scan the USE middlewares.
+// A USE path reaching into the parameters of the pattern only matches some
+// of the requests of the route, Ctx.Next checks it against the request
+// path.
+func (r *Mux) middlewareChain(rt Route, pattern string) []Middleware {
+	chain := make([]Middleware, 0, len(r.middlewares)+len(rt.middlewares))
+	for _, middleware := range r.middlewares {
+		switch {
+		case pathMatch(middleware.Path, pattern):
+			chain = append(chain, middleware)
+		case patternMayMatch(middleware.Path, pattern):
+			middleware.checkPath = true
+			chain = append(chain, middleware)
+		}
+	}
+
+	return append(chain, rt.middlewares...)
+}
+
+// patternMayMatch reports whether middlewarePath matches some of the
+// request paths of pattern, because it covers one of its parameters or its
+// catch-all.
+func patternMayMatch(middlewarePath, pattern string) bool {
+	middlewareParts := strings.Split(strings.Trim(middlewarePath, "/"), "/")
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+
+	dynamic := false
+	for i, part := range middlewareParts {
+		if i >= len(patternParts) {
+			return false
+		}
+
+		switch segment := patternParts[i]; {
+		case strings.HasPrefix(segment, "*"):
+			return true
+		case strings.HasPrefix(segment, ":"):
+			dynamic = true
+		case segment != part:
+			return false
+		}
+	}
+
+	return dynamic
+}
+
+// serve runs the middleware chain and handler on c, then puts c back in
+// the pool, also when a handler panics, so the temporary file of a large
+// body is removed. Handlers must not keep a reference to c once they
+// returned.
+func (r *Mux) serve(c *Ctx, route string, middlewares []Middleware, handler CtxFunc) {
+	defer r.releaseCtx(c)
+
+	c.routerPath = route
+	c.middlewares = middlewares
+	c.handler = handler
+
+	if err := c.Next(); err != nil {
+		r.handleError(c, err)
+	}
+}
+
+// acquireCtx returns a Ctx from the pool, reset for the request.
+func (r *Mux) acquireCtx(w http.ResponseWriter, req *http.Request) *Ctx {
+	c, _ := r.pool.Get().(*Ctx)
+	if c == nil {
+		c = &Ctx{
+			w:      &responseWriter{},
+			keys:   make(map[string]any),
+			params: make(Params, 0, 4),
+		}
+	}
+
+	c.reset(w, req, r)
+	return c
+}
+
+func (r *Mux) releaseCtx(c *Ctx) {
+	c.reset(nil, nil, nil)
+	r.pool.Put(c)
+}
+
+// allowedMethods returns the methods having a route matching path. HEAD
+// is implied by GET and OPTIONS is always answered, so both are included
+// as soon as any route matches.
+func (r *Mux) allowedMethods(path string) []string {
+	var matched [OPTIONS + 1]bool
+	var params Params
+	found := false
+
 	for method := GET; method <= OPTIONS; method++ {
-		if r.trees[method] != nil {
-			if methodToString[method] == req.Method {
-				treeMethod := r.trees[method]
-				treeStartNode := treeMethod.startNode
-				handler, ctxHandler, params := treeStartNode.InDepthSearch(req.URL.Path)
-
-				if handler != nil {
-					middlewares := findMatchingMiddleware(r.middlewares, req.URL.Path)
-					var handlerFunc CtxFunc
-
-					ctx := &Ctx{
-						w:               w,
-						r:               req,
-						keys:            make(map[string]any),
-						params:          params,
-						routerPath:      req.URL.Path,
-						middlewareIndex: -1,
-						middlewares:     middlewares,
-						handler:         handlerFunc,
-						automatic:       r.automatic,
-						maxMemory:       10 << 20, // 10 MB
-						formParsed:      false,
-					}
+		if node := r.lookup(method, path, &params); node != nil {
+			matched[method] = true
+			found = true
+			params = params[:0]
+		}
+	}
 
-					if r.automatic {
-						handlerFunc = ctxHandler
+	if !found {
+		return nil
+	}
 
-						for _, middleware := range middlewares {
-							if pathMatch(middleware.Path, req.URL.Path) {
-								if err := middleware.Handler(ctx); err != nil {
-									http.Error(w, err.Error(), http.StatusInternalServerError)
-									return
-								}
-							}
-						}
-					} else {
-						handlerFunc = func(c *Ctx) error {
-							c.middlewares = middlewares
-							c.middlewareIndex = -1
-							c.handler = ctxHandler
-							c.automatic = r.automatic
-							return c.Next()
-						}
+	matched[HEAD] = matched[HEAD] || matched[GET]
+	matched[OPTIONS] = true
 
-						handlerFunc(ctx)
-					}
-					handler(w, req)
-					return
-				}
-			}
+	allowed := make([]string, 0, len(matched))
+	for method := GET; method <= OPTIONS; method++ {
+		if matched[method] {
+			allowed = append(allowed, methodToString[method])
 		}
 	}
-	http.NotFound(w, req)
+
+	return allowed
 }
 
-// ...existing code...
+// NotFound sets the handler called when no route matches the request.
+// By default a 404 HTTPError is returned to the error handler.
+func (r *Mux) NotFound(handler CtxFunc) {
+	r.notFound = handler
+}
+
+// MethodNotAllowed sets the handler called when the request path matches
+// routes of other methods only. The Allow header is already set when the
+// handler runs. By default a 405 HTTPError is returned to the error handler.
+func (r *Mux) MethodNotAllowed(handler CtxFunc) {
+	r.methodNotAllowed = handler
+}
+
+func defaultNotFound(c *Ctx) error {
+	return NewHTTPError(NotFound, "")
+}
+
+func defaultMethodNotAllowed(c *Ctx) error {
+	return NewHTTPError(MethodNotAllowed, "")
+}
+
+func defaultOptions(c *Ctx) error {
+	return c.Status(NoContent)
+}
 
 func findMatchingMiddleware(middlewares []Middleware, path string) []Middleware {
 	var matchingMiddleware []Middleware
@@ -216,6 +410,14 @@ func findMatchingMiddleware(middlewares []Middleware, path string) []Middleware
 
 // buildTrees() must be done after init of the tree
 
+// initTrees builds the trees once, when the server starts or on the first
+// request, so concurrent requests don't race to build them.
+func (r *Mux) initTrees() {
+	r.treesOnce.Do(func() {
+		r.trees = r.buildTrees()
+	})
+}
+
 func (r *Mux) setMiddlewareAutomatically(automatic bool) {
 	r.automatic = automatic
 }
@@ -233,10 +435,10 @@ func (r *Mux) buildTrees() map[Method]*Tree {
 			for _, handler := range *handlers {
 				t := trees[method]
 				var err error
-				t, err = r.createTreeIdea(handler, t, method)
+				t, err = r.createTreeIdea(*handler, t, method)
 				if err != nil {
-					log.Println("[ERROR] Duplicate routes:", err)
-					log.Printf("[WARNING] Skipping route: %s, the second one wrote \n", handler.path)
+					log.Println("[ERROR] Failed to register route:", err)
+					log.Printf("[WARNING] Skipping route: %s %s\n", method, handler.path)
 					continue
 				}
 				trees[method] = t
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestInitMux(t *testing.T) {
@@ -28,10 +30,6 @@ func TestInitMux(t *testing.T) {
 	if mux.middlewares == nil {
 		t.Error("middlewares slice not initialized")
 	}
-
-	if mux.handlerMap == nil {
-		t.Error("handlerMap not initialized")
-	}
 }
 
 func TestMux_GET(t *testing.T) {
@@ -551,3 +549,588 @@ func TestMux_ConcurrentRequests(t *testing.T) {
 		<-done
 	}
 }
+
+func TestMux_Group(t *testing.T) {
+	mux := InitMux()
+
+	groupMiddleware := func(c *Ctx) error {
+		c.SetHeader("X-Group", "api")
+		return c.Next()
+	}
+
+	nestedMiddleware := func(c *Ctx) error {
+		c.SetHeader("X-Nested", "v1")
+		return c.Next()
+	}
+
+	api := mux.Group("/api", groupMiddleware)
+	v1 := api.Group("/v1", nestedMiddleware)
+
+	v1.GET("/users/:id", func(c *Ctx) error {
+		id, _ := c.GetURLParam("id")
+		return c.SendString("user "+id, 200)
+	})
+	api.POST("/login", func(c *Ctx) error {
+		return c.SendString("login", 200)
+	})
+	mux.GET("/health", func(c *Ctx) error {
+		return c.SendString("ok", 200)
+	})
+
+	if v1.Prefix() != "/api/v1" {
+		t.Errorf("Expected prefix '/api/v1', got '%s'", v1.Prefix())
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/users/42", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "user 42" {
+		t.Errorf("Expected 'user 42', got '%s'", string(body))
+	}
+	if resp.Header.Get("X-Group") != "api" || resp.Header.Get("X-Nested") != "v1" {
+		t.Errorf("Group middlewares not applied: %v", resp.Header)
+	}
+
+	req = httptest.NewRequest("POST", "/api/login", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp = w.Result()
+	if resp.Header.Get("X-Group") != "api" {
+		t.Error("Group middleware not applied to group route")
+	}
+	if resp.Header.Get("X-Nested") != "" {
+		t.Error("Nested group middleware leaked to parent group")
+	}
+
+	req = httptest.NewRequest("GET", "/health", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp = w.Result()
+	if resp.Header.Get("X-Group") != "" {
+		t.Error("Group middleware leaked to route outside the group")
+	}
+}
+
+func TestGroup_USE(t *testing.T) {
+	mux := InitMux()
+
+	admin := mux.Group("/admin")
+	admin.USE("", func(c *Ctx) error {
+		c.SetHeader("X-Admin", "true")
+		return c.Next()
+	})
+	admin.GET("/dashboard", func(c *Ctx) error {
+		return c.SendString("dashboard", 200)
+	})
+	mux.GET("/administrator", func(c *Ctx) error {
+		return c.SendString("other", 200)
+	})
+
+	req := httptest.NewRequest("GET", "/admin/dashboard", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Result().Header.Get("X-Admin") != "true" {
+		t.Error("Group USE middleware not applied")
+	}
+
+	req = httptest.NewRequest("GET", "/administrator", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Result().Header.Get("X-Admin") != "" {
+		t.Error("Group USE middleware applied outside of the group prefix")
+	}
+}
+
+func TestJoinPaths(t *testing.T) {
+	tests := []struct {
+		prefix, path, expected string
+	}{
+		{"", "/", "/"},
+		{"/api", "", "/api"},
+		{"/api", "/", "/api"},
+		{"/api/", "/users", "/api/users"},
+		{"api", "users/", "/api/users"},
+		{"/api/v1", "/users/:id", "/api/v1/users/:id"},
+	}
+
+	for _, tt := range tests {
+		if got := joinPaths(tt.prefix, tt.path); got != tt.expected {
+			t.Errorf("joinPaths(%q, %q) = %q, expected %q", tt.prefix, tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestMux_SharedCtx(t *testing.T) {
+	for _, automatic := range []bool{false, true} {
+		mux := InitMux()
+		mux.setMiddlewareAutomatically(automatic)
+
+		var middlewareCtx *Ctx
+		mux.USE("/", func(c *Ctx) error {
+			middlewareCtx = c
+			c.SetKey("user", "john")
+			if automatic {
+				return nil
+			}
+			return c.Next()
+		})
+
+		mux.GET("/users/:id", func(c *Ctx) error {
+			if c != middlewareCtx {
+				t.Errorf("automatic=%v: handler received a different Ctx than the middleware", automatic)
+			}
+
+			user, err := c.GetStringKey("user")
+			if err != nil {
+				return c.SendString("missing key", 500)
+			}
+			id, _ := c.GetURLParam("id")
+			return c.SendString(user+" "+id, 200)
+		})
+
+		req := httptest.NewRequest("GET", "/users/7", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		body, _ := io.ReadAll(w.Result().Body)
+		if string(body) != "john 7" {
+			t.Errorf("automatic=%v: expected 'john 7', got '%s'", automatic, string(body))
+		}
+	}
+}
+
+func TestMux_MiddlewareStopsChain(t *testing.T) {
+	mux := InitMux()
+
+	handlerCalled := false
+	mux.USE("/", func(c *Ctx) error {
+		return c.SendString("unauthorized", 401)
+	})
+	mux.GET("/private", func(c *Ctx) error {
+		handlerCalled = true
+		return c.SendString("secret", 200)
+	})
+
+	req := httptest.NewRequest("GET", "/private", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if handlerCalled {
+		t.Error("Handler should not run when a middleware doesn't call Next")
+	}
+	if w.Result().StatusCode != 401 {
+		t.Errorf("Expected status 401, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestMux_USEWithinParams(t *testing.T) {
+	for _, automatic := range []bool{false, true} {
+		mux := InitMux()
+		mux.setMiddlewareAutomatically(automatic)
+
+		tag := func(name string) CtxFunc {
+			return func(c *Ctx) error {
+				c.SetHeader("X-Middlewares", c.w.Header().Get("X-Middlewares")+name)
+				if automatic {
+					return nil
+				}
+				return c.Next()
+			}
+		}
+
+		mux.USE("/users", tag("u"))
+		mux.USE("/users/admin", tag("a"))
+		mux.USE("/files/private", tag("f"))
+		mux.GET("/users/:id", func(c *Ctx) error {
+			return c.SendString("user", OK)
+		})
+		mux.GET("/files/*path", func(c *Ctx) error {
+			return c.SendString("file", OK)
+		})
+
+		tests := []struct {
+			path     string
+			expected string
+		}{
+			{"/users/7", "u"},
+			{"/users/admin", "ua"},
+			{"/files/public/a.txt", ""},
+			{"/files/private/a.txt", "f"},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != OK || w.Header().Get("X-Middlewares") != tt.expected {
+				t.Errorf("automatic=%v %s: expected middlewares %q, got %d %q", automatic, tt.path, tt.expected, w.Code, w.Header().Get("X-Middlewares"))
+			}
+		}
+	}
+}
+
+func TestMux_CatchAllParam(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/assets/:version/*filepath", func(c *Ctx) error {
+		path, err := c.GetURLParam("filepath")
+		if err != nil {
+			return err
+		}
+
+		params, err := c.GetAllParams()
+		if err != nil {
+			return err
+		}
+		return c.SendString(params["version"]+" "+path, 200)
+	})
+
+	req := httptest.NewRequest("GET", "/assets/v2/js/vendor/app.js", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != "v2 js/vendor/app.js" {
+		t.Errorf("Expected 'v2 js/vendor/app.js', got '%s'", string(body))
+	}
+}
+
+func TestMux_MethodNotAllowed(t *testing.T) {
+	mux := InitMux()
+
+	handler := func(c *Ctx) error {
+		return c.SendString("OK", 200)
+	}
+
+	mux.GET("/users/:id", handler)
+	mux.PUT("/users/:id", handler)
+	mux.DELETE("/users/:id", handler)
+	mux.POST("/users", handler)
+
+	req := httptest.NewRequest("POST", "/users/1", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != MethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+	if allow := resp.Header.Get("Allow"); allow != "GET, PUT, DELETE, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow 'GET, PUT, DELETE, HEAD, OPTIONS', got '%s'", allow)
+	}
+
+	req = httptest.NewRequest("GET", "/posts", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp = w.Result()
+	if resp.StatusCode != NotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+	if allow := resp.Header.Get("Allow"); allow != "" {
+		t.Errorf("Expected no Allow header on 404, got '%s'", allow)
+	}
+}
+
+func TestMux_CustomNotFoundAndMethodNotAllowed(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/exists", func(c *Ctx) error {
+		return c.SendString("OK", 200)
+	})
+	mux.NotFound(func(c *Ctx) error {
+		return c.SendString("nothing at "+c.Path(), NotFound)
+	})
+	mux.MethodNotAllowed(func(c *Ctx) error {
+		return c.SendString("use "+c.HeaderSent().Get("Allow"), MethodNotAllowed)
+	})
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Result().StatusCode != NotFound || string(body) != "nothing at /missing" {
+		t.Errorf("Custom NotFound handler not used: %d '%s'", w.Result().StatusCode, string(body))
+	}
+
+	req = httptest.NewRequest("DELETE", "/exists", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ = io.ReadAll(w.Result().Body)
+	if w.Result().StatusCode != MethodNotAllowed || string(body) != "use GET, HEAD, OPTIONS" {
+		t.Errorf("Custom MethodNotAllowed handler not used: %d '%s'", w.Result().StatusCode, string(body))
+	}
+}
+
+func TestMux_AutomaticHEAD(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/users/:id", func(c *Ctx) error {
+		c.SetHeader("X-User", "found")
+		return c.SendString("user body", 200)
+	})
+
+	req := httptest.NewRequest("HEAD", "/users/1", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-User") != "found" {
+		t.Error("Expected GET handler headers on HEAD response")
+	}
+	if len(body) != 0 {
+		t.Errorf("Expected empty body for HEAD, got '%s'", string(body))
+	}
+
+	mux = InitMux()
+	mux.GET("/ping", func(c *Ctx) error {
+		return c.SendString("get", 200)
+	})
+	mux.HEAD("/ping", func(c *Ctx) error {
+		c.SetHeader("X-Explicit", "head")
+		return c.Status(204)
+	})
+
+	req = httptest.NewRequest("HEAD", "/ping", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != 204 || w.Result().Header.Get("X-Explicit") != "head" {
+		t.Error("Explicit HEAD route should override the GET handler")
+	}
+}
+
+func TestMux_AutomaticOPTIONS(t *testing.T) {
+	mux := InitMux()
+
+	handler := func(c *Ctx) error {
+		return c.SendString("OK", 200)
+	}
+
+	mux.GET("/items", handler)
+	mux.POST("/items", handler)
+	mux.OPTIONS("/custom", func(c *Ctx) error {
+		return c.SendString("custom options", 200)
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != NoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if allow := resp.Header.Get("Allow"); allow != "GET, POST, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow 'GET, POST, HEAD, OPTIONS', got '%s'", allow)
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/custom", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != "custom options" {
+		t.Errorf("Explicit OPTIONS route should override, got '%s'", string(body))
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/missing", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != NotFound {
+		t.Errorf("Expected status 404 for OPTIONS on unknown path, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestMux_NamedRegexParam(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/orders/:id|[0-9]+|", func(c *Ctx) error {
+		id, err := c.GetURLParam("id")
+		if err != nil {
+			return err
+		}
+
+		regexValue, err := c.RegexURLParam(1)
+		if err != nil || regexValue != id {
+			return c.SendString("regex param mismatch", 500)
+		}
+		return c.SendString("order "+id, 200)
+	})
+
+	req := httptest.NewRequest("GET", "/orders/99", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != "order 99" {
+		t.Errorf("Expected 'order 99', got '%s'", string(body))
+	}
+
+	req = httptest.NewRequest("GET", "/orders/abc", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != NotFound {
+		t.Errorf("Expected 404 for a non-matching regex param, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestMux_ParamConstraints(t *testing.T) {
+	mux := InitMux()
+
+	type OrderURI struct {
+		ID   int       `uri:"id"`
+		User uuid.UUID `uri:"user"`
+	}
+
+	mux.GET("/users/:user<uuid>/orders/:id<int>", func(c *Ctx) error {
+		id, err := c.GetParamInt("id")
+		if err != nil {
+			return err
+		}
+		user, err := c.GetParamUUID("user")
+		if err != nil {
+			return err
+		}
+
+		var order OrderURI
+		if err := c.BindURI(&order); err != nil {
+			return err
+		}
+		if order.ID != id || order.User != user {
+			return c.SendString("binding mismatch", 500)
+		}
+		return c.SendString(fmt.Sprintf("%s %d", user, id), 200)
+	})
+
+	req := httptest.NewRequest("GET", "/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8/orders/12", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != "6ba7b810-9dad-11d1-80b4-00c04fd430c8 12" {
+		t.Errorf("Unexpected body '%s'", string(body))
+	}
+
+	req = httptest.NewRequest("GET", "/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8/orders/twelve", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != NotFound {
+		t.Errorf("Expected 404 for a param violating its constraint, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestMux_UnknownConstraintPanics(t *testing.T) {
+	mux := InitMux()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registration of an unknown constraint to panic")
+		}
+	}()
+	mux.GET("/orders/:id<number>", func(c *Ctx) error { return nil })
+}
+
+func TestMux_InvalidRegexPanics(t *testing.T) {
+	mux := InitMux()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registration of an invalid regex to panic")
+		}
+	}()
+	mux.GET("/users/:id|[0-9+|", func(c *Ctx) error { return nil })
+}
+
+func TestMux_RouteMiddlewares(t *testing.T) {
+	mux := InitMux()
+
+	var order []string
+	track := func(name string) func(*Ctx) error {
+		return func(c *Ctx) error {
+			order = append(order, name)
+			return c.Next()
+		}
+	}
+
+	mux.USE("/", track("global"))
+	api := mux.Group("/api", track("group"))
+	api.GET("/items", func(c *Ctx) error {
+		order = append(order, "handler")
+		return c.SendString("items", 200)
+	}, track("route1"), track("route2"))
+	api.POST("/items", func(c *Ctx) error {
+		order = append(order, "handler")
+		return c.SendString("created", 201)
+	})
+
+	req := httptest.NewRequest("GET", "/api/items", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	expected := "global,group,route1,route2,handler"
+	if got := strings.Join(order, ","); got != expected {
+		t.Errorf("Expected order %s, got %s", expected, got)
+	}
+
+	order = nil
+	req = httptest.NewRequest("POST", "/api/items", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	expected = "global,group,handler"
+	if got := strings.Join(order, ","); got != expected {
+		t.Errorf("Route middlewares leaked to another method: expected %s, got %s", expected, got)
+	}
+}
+
+func TestMux_PooledCtxIsReset(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/users/:id", func(c *Ctx) error {
+		if _, err := c.GetKey("seen"); err == nil {
+			return c.SendString("stale key", 500)
+		}
+		c.SetKey("seen", true)
+
+		id, _ := c.GetURLParam("id")
+		return c.SendString(id, 200)
+	})
+	mux.GET("/about", func(c *Ctx) error {
+		if _, err := c.GetURLParam("id"); err == nil {
+			return c.SendString("stale param", 500)
+		}
+		return c.SendString("about", 200)
+	})
+
+	for _, tt := range []struct{ path, body string }{
+		{"/users/1", "1"},
+		{"/users/2", "2"},
+		{"/about", "about"},
+		{"/users/3", "3"},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != 200 || w.Body.String() != tt.body {
+			t.Errorf("%s: expected 200 %q, got %d %q", tt.path, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package tree
+
+// Param is a single route parameter captured while routing.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params holds the route parameters in the order they appear in the path.
+// Routes rarely have more than a few parameters, so a slice is scanned
+// instead of building a map on every request.
+type Params []Param
+
+// Get returns the value of the first parameter named key.
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Map copies the parameters into a map, for callers such as the binding
+// package that work with maps.
+func (ps Params) Map() map[string]string {
+	m := make(map[string]string, len(ps))
+	for _, p := range ps {
+		if _, ok := m[p.Key]; !ok {
+			m[p.Key] = p.Value
+		}
+	}
+
+	return m
+}
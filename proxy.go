@@ -0,0 +1,53 @@
+package tree
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// SetTrustedProxies lists the reverse proxies in front of the server, as IP
+// addresses or CIDR ranges, e.g. "10.0.0.0/8". Ctx.ClientIP only reads the
+// X-Forwarded-For and X-Real-IP headers of requests coming from them, any
+// client can set the headers otherwise. Calling it without arguments trusts
+// no proxy, the default.
+func (r *Mux) SetTrustedProxies(proxies ...string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	r.trustedProxies = prefixes
+	return nil
+}
+
+// trustedProxy reports whether ip belongs to one of the trusted proxies.
+func (r *Mux) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range r.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
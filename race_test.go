@@ -0,0 +1,7 @@
+//go:build race
+
+package tree
+
+// raceEnabled skips the allocation tests, sync.Pool drops values at random
+// under the race detector.
+const raceEnabled = true
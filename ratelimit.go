@@ -0,0 +1,301 @@
+package tree
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit requests, the bucket is
+	// refilled continuously at Limit tokens per Window.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests in any Window, estimated from the
+	// counts of the current and the previous fixed window.
+	SlidingWindow
+)
+
+// RateLimitRule describes the quota applied to a key.
+type RateLimitRule struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Window    time.Duration
+}
+
+// RateLimitResult is the outcome of taking a request from a key's quota.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time left until the full quota is available again.
+	Reset time.Duration
+	// RetryAfter is the time left until the next request is allowed, set
+	// when the request is rejected.
+	RetryAfter time.Duration
+}
+
+// Store keeps the rate limit state of the keys. MemoryStore is used by
+// default, external backends (e.g. Redis) can be plugged in to share the
+// quotas between several server instances.
+type Store interface {
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+type RateLimitConfig struct {
+	Algorithm RateLimitAlgorithm
+	// Limit is the number of requests allowed per Window, 100 by default.
+	Limit int
+	// Window is the period of the quota, one minute by default.
+	Window time.Duration
+	// KeyFunc returns the key the quota is counted for, the client IP by
+	// default. Requests with an empty key are not limited.
+	KeyFunc func(*Ctx) string
+	// Store keeps the quotas, a new MemoryStore by default.
+	Store   Store
+	Skipper func(*Ctx) bool
+}
+
+// RateLimit limits the number of requests per key. The RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers are set on every
+// response, rejected requests also get Retry-After and a 429 HTTPError is
+// returned to the error handler.
+func RateLimit(config ...RateLimitConfig) func(*Ctx) error {
+	cfg := UseDefaultRateLimit()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Limit <= 0 {
+		cfg.Limit = 100
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = RateLimitByIP
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+
+	rule := RateLimitRule{
+		Algorithm: cfg.Algorithm,
+		Limit:     cfg.Limit,
+		Window:    cfg.Window,
+	}
+
+	return func(c *Ctx) error {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+
+		key := cfg.KeyFunc(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		res, err := cfg.Store.Take(c.r.Context(), key, rule)
+		if err != nil {
+			return err
+		}
+
+		c.SetHeader("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.SetHeader("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.SetHeader("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			c.SetHeader("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			return NewHTTPError(ManyRequests, "")
+		}
+
+		return c.Next()
+	}
+}
+
+// RateLimitByIP counts the quota per client IP, as returned by
+// Ctx.ClientIP. Forwarding headers are ignored unless the request comes
+// from a proxy set with Mux.SetTrustedProxies.
+func RateLimitByIP(c *Ctx) string {
+	return c.ClientIP()
+}
+
+// RateLimitByHeader counts the quota per value of the request header, e.g.
+// an API key. Requests without the header are not limited.
+func RateLimitByHeader(header string) func(*Ctx) string {
+	return func(c *Ctx) string {
+		return c.Header().Get(header)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+func UseDefaultRateLimit() RateLimitConfig {
+	return RateLimitConfig{
+		Algorithm: TokenBucket,
+		Limit:     100,
+		Window:    time.Minute,
+		KeyFunc:   RateLimitByIP,
+	}
+}
+
+const (
+	memoryStoreShards = 64
+	memoryStoreSweep  = time.Minute
+)
+
+// MemoryStore is an in-process Store. The keys are spread over shards with
+// their own lock, and keys whose quota is full again are dropped lazily.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryStoreShards]memoryShard
+	now    func() time.Time
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+type rateLimitEntry struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+	// sliding window
+	start    time.Time
+	previous int
+	current  int
+
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		seed: maphash.MakeSeed(),
+		now:  time.Now,
+	}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*rateLimitEntry)
+	}
+
+	return s
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	now := s.now()
+	shard := &s.shards[maphash.String(s.seed, key)%memoryStoreShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) >= memoryStoreSweep {
+		for k, e := range shard.entries {
+			if now.After(e.expires) {
+				delete(shard.entries, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	e, ok := shard.entries[key]
+	if !ok {
+		e = &rateLimitEntry{
+			tokens: float64(rule.Limit),
+			last:   now,
+			start:  now,
+		}
+		shard.entries[key] = e
+	}
+
+	if rule.Algorithm == SlidingWindow {
+		return e.takeSlidingWindow(now, rule), nil
+	}
+	return e.takeTokenBucket(now, rule), nil
+}
+
+func (e *rateLimitEntry) takeTokenBucket(now time.Time, rule RateLimitRule) RateLimitResult {
+	limit := float64(rule.Limit)
+	perToken := max(1, rule.Window/time.Duration(rule.Limit))
+
+	e.tokens = min(limit, e.tokens+float64(now.Sub(e.last))/float64(perToken))
+	e.last = now
+
+	res := RateLimitResult{Limit: rule.Limit}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - e.tokens) * float64(perToken))
+	}
+
+	res.Remaining = int(e.tokens)
+	res.Reset = time.Duration((limit - e.tokens) * float64(perToken))
+	e.expires = now.Add(res.Reset)
+
+	return res
+}
+
+func (e *rateLimitEntry) takeSlidingWindow(now time.Time, rule RateLimitRule) RateLimitResult {
+	window := rule.Window
+
+	if elapsed := now.Sub(e.start); elapsed >= window {
+		if elapsed >= 2*window {
+			e.previous = 0
+		} else {
+			e.previous = e.current
+		}
+		e.current = 0
+		e.start = e.start.Add(elapsed.Truncate(window))
+	}
+
+	elapsed := now.Sub(e.start)
+	weight := float64(window-elapsed) / float64(window)
+	count := float64(e.previous)*weight + float64(e.current)
+
+	res := RateLimitResult{
+		Limit: rule.Limit,
+		Reset: window - elapsed,
+	}
+
+	if count+1 <= float64(rule.Limit) {
+		e.current++
+		res.Allowed = true
+		res.Remaining = max(0, rule.Limit-int(math.Ceil(count+1)))
+	} else {
+		res.RetryAfter = e.retryAfter(elapsed, rule)
+	}
+
+	if e.current > 0 {
+		res.Reset += window
+	}
+	e.expires = now.Add(res.Reset)
+
+	return res
+}
+
+// retryAfter returns the time left until the weighted count of the
+// sliding window drops enough to accept one more request.
+func (e *rateLimitEntry) retryAfter(elapsed time.Duration, rule RateLimitRule) time.Duration {
+	window := float64(rule.Window)
+	free := float64(rule.Limit - 1)
+
+	// still in the current window, waiting for the previous one to fade
+	if e.current <= rule.Limit-1 && e.previous > 0 {
+		at := window * (1 - (free-float64(e.current))/float64(e.previous))
+		return time.Duration(at) - elapsed
+	}
+
+	// in the next window the current count becomes the previous one
+	at := window * (1 - free/float64(e.current))
+	return rule.Window - elapsed + time.Duration(at)
+}
@@ -0,0 +1,269 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time          { return f.now }
+func (f *fakeClock) Advance(d time.Duration) { f.now = f.now.Add(d) }
+
+func newTestStore(clock *fakeClock) *MemoryStore {
+	s := NewMemoryStore()
+	s.now = clock.Now
+	return s
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store := newTestStore(clock)
+	rule := RateLimitRule{Algorithm: TokenBucket, Limit: 3, Window: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		res, _ := store.Take(context.Background(), "k", rule)
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("Expected allowed with %d remaining, got %+v", i, res)
+		}
+	}
+
+	res, _ := store.Take(context.Background(), "k", rule)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Fatalf("Expected rejection with retry after 1s and reset 3s, got %+v", res)
+	}
+
+	// one token is refilled per second
+	clock.Advance(time.Second)
+	if res, _ := store.Take(context.Background(), "k", rule); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("Expected a refilled token, got %+v", res)
+	}
+
+	// other keys have their own bucket
+	if res, _ := store.Take(context.Background(), "other", rule); !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("Expected a fresh bucket for another key, got %+v", res)
+	}
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store := newTestStore(clock)
+	rule := RateLimitRule{Algorithm: SlidingWindow, Limit: 4, Window: 10 * time.Second}
+
+	for i := 0; i < 4; i++ {
+		if res, _ := store.Take(context.Background(), "k", rule); !res.Allowed {
+			t.Fatalf("Request %d: expected allowed, got %+v", i, res)
+		}
+	}
+
+	res, _ := store.Take(context.Background(), "k", rule)
+	if res.Allowed {
+		t.Fatalf("Expected the 5th request to be rejected, got %+v", res)
+	}
+	// the 4 requests weigh 4*(1-x) in the next window, x >= 1/4 allows one more
+	if res.RetryAfter != 12500*time.Millisecond {
+		t.Errorf("Expected retry after 12.5s, got %v", res.RetryAfter)
+	}
+
+	// half way through the next window the previous count weighs 2
+	clock.Advance(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Take(context.Background(), "k", rule); !res.Allowed {
+			t.Fatalf("Expected allowed after the window slid, got %+v", res)
+		}
+	}
+	if res, _ := store.Take(context.Background(), "k", rule); res.Allowed {
+		t.Fatalf("Expected rejection once the weighted count reached the limit, got %+v", res)
+	}
+
+	// two windows later the counts are forgotten
+	clock.Advance(20 * time.Second)
+	if res, _ := store.Take(context.Background(), "k", rule); !res.Allowed || res.Remaining != 3 {
+		t.Fatalf("Expected a fresh window, got %+v", res)
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store := newTestStore(clock)
+	rule := RateLimitRule{Algorithm: TokenBucket, Limit: 10, Window: time.Second}
+
+	idx := maphash.String(store.seed, "stale") % memoryStoreShards
+	shard := &store.shards[idx]
+
+	// a key stored in the same shard as "stale"
+	fresh := ""
+	for i := 0; fresh == ""; i++ {
+		if key := strconv.Itoa(i); maphash.String(store.seed, key)%memoryStoreShards == idx {
+			fresh = key
+		}
+	}
+
+	store.Take(context.Background(), "stale", rule)
+	if _, ok := shard.entries["stale"]; !ok {
+		t.Fatal("Expected the key to be stored")
+	}
+
+	// the next access to the shard after the sweep interval drops the
+	// keys whose bucket is full again
+	clock.Advance(memoryStoreSweep)
+	store.Take(context.Background(), fresh, rule)
+
+	if _, ok := shard.entries["stale"]; ok {
+		t.Error("Expected the stale key to be dropped")
+	}
+	if _, ok := shard.entries[fresh]; !ok {
+		t.Error("Expected the active key to be kept")
+	}
+}
+
+func TestMemoryStore_Concurrent(t *testing.T) {
+	store := NewMemoryStore()
+	rule := RateLimitRule{Algorithm: TokenBucket, Limit: 50, Window: time.Hour}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, _ := store.Take(context.Background(), "k", rule); res.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 50 {
+		t.Errorf("Expected exactly 50 allowed requests, got %d", allowed)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+
+	mux := InitMux()
+	mux.USE("/", RateLimit(RateLimitConfig{
+		Limit:  2,
+		Window: time.Minute,
+		Store:  newTestStore(clock),
+	}))
+	mux.GET("/users", func(c *Ctx) error {
+		return c.SendString("users", OK)
+	})
+
+	send := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/users", nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := send("10.0.0.1:1000")
+		if w.Code != OK {
+			t.Fatalf("Request %d: expected 200, got %d", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != remaining {
+			t.Errorf("Request %d: unexpected headers %v", i, w.Header())
+		}
+	}
+
+	w := send("10.0.0.1:1000")
+	if w.Code != ManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("Unexpected headers on rejection: %v", w.Header())
+	}
+
+	if w := send("10.0.0.2:1000"); w.Code != OK {
+		t.Errorf("Expected another IP to have its own quota, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_ForwardedFor(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", RateLimit(RateLimitConfig{Limit: 1, Window: time.Minute}))
+	mux.GET("/users", func(c *Ctx) error {
+		return c.SendString("users", OK)
+	})
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/users", nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		req.Header.Set("X-Forwarded-For", "1.2.3."+strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+
+	if codes[0] != OK || codes[1] != ManyRequests || codes[2] != ManyRequests {
+		t.Errorf("Expected forged X-Forwarded-For headers to share the quota, got %v", codes)
+	}
+}
+
+func TestRateLimit_KeyFunc(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", RateLimit(RateLimitConfig{
+		Algorithm: SlidingWindow,
+		Limit:     1,
+		Window:    time.Minute,
+		KeyFunc:   RateLimitByHeader("X-API-Key"),
+	}))
+	mux.GET("/users", func(c *Ctx) error {
+		return c.SendString("users", OK)
+	})
+
+	send := func(key string) int {
+		req := httptest.NewRequest("GET", "/users", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if send("a") != OK || send("a") != ManyRequests || send("b") != OK {
+		t.Error("Expected the quota to be counted per API key")
+	}
+	if send("") != OK || send("") != OK {
+		t.Error("Expected requests without key not to be limited")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, RateLimitRule) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimit_StoreError(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", RateLimit(RateLimitConfig{Store: failingStore{}}))
+	mux.GET("/users", func(c *Ctx) error {
+		return c.SendString("users", OK)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+
+	if w.Code != InternalError {
+		t.Errorf("Expected 500 when the store fails, got %d", w.Code)
+	}
+}
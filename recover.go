@@ -0,0 +1,79 @@
+package tree
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+type RecoverConfig struct {
+	// Logger receives the recovered panics with their stack trace,
+	// slog.Default() by default.
+	Logger *slog.Logger
+	// Development adds the panic value and the stack trace to the Details
+	// of the 500 error sent to the client. Don't enable it in production.
+	Development bool
+	Skipper     func(*Ctx) bool
+}
+
+// Recover turns a panic raised further down the chain into a 500 HTTPError
+// returned to the error handler, and logs it together with the stack trace.
+//
+// http.ErrAbortHandler is panicked again, so net/http aborts the response
+// as the handler asked for.
+func Recover(config ...RecoverConfig) func(*Ctx) error {
+	cfg := UseDefaultRecover()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return func(c *Ctx) (err error) {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			stack := debug.Stack()
+
+			logger := cfg.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger.LogAttrs(c.r.Context(), slog.LevelError, "panic recovered",
+				slog.Any("panic", rec),
+				slog.String("method", c.GetMethod()),
+				slog.String("route", c.RoutePattern()),
+				slog.String("path", c.Path()),
+				slog.String("stack", string(stack)),
+			)
+
+			httpErr := NewHTTPError(InternalError, "")
+			if cfg.Development {
+				httpErr.Details = J{
+					"panic": fmt.Sprint(rec),
+					"stack": string(stack),
+				}
+			}
+			err = httpErr
+		}()
+
+		return c.Next()
+	}
+}
+
+func UseDefaultRecover() RecoverConfig {
+	return RecoverConfig{
+		Logger:      nil,
+		Development: false,
+	}
+}
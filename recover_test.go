@@ -0,0 +1,99 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	var out bytes.Buffer
+
+	mux := InitMux()
+	mux.USE("/", Recover(RecoverConfig{Logger: slog.New(slog.NewTextHandler(&out, nil))}))
+	mux.GET("/panic", func(c *Ctx) error {
+		panic("something broke")
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	if w.Code != InternalError {
+		t.Errorf("Expected 500, got %d", w.Code)
+	}
+
+	var body struct {
+		Error HTTPError `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error, got %q: %v", w.Body.String(), err)
+	}
+	if body.Error.Details != nil {
+		t.Errorf("Expected no details outside development mode, got %v", body.Error.Details)
+	}
+	if strings.Contains(w.Body.String(), "something broke") {
+		t.Errorf("Expected the panic value not to leak, got %q", w.Body.String())
+	}
+
+	log := out.String()
+	if !strings.Contains(log, "panic recovered") || !strings.Contains(log, "something broke") || !strings.Contains(log, "goroutine") {
+		t.Errorf("Expected the panic and its stack to be logged, got %q", log)
+	}
+}
+
+func TestRecover_Development(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", Recover(RecoverConfig{
+		Logger:      slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		Development: true,
+	}))
+	mux.GET("/panic", func(c *Ctx) error {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	var body struct {
+		Error struct {
+			Code    int               `json:"code"`
+			Details map[string]string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error, got %q: %v", w.Body.String(), err)
+	}
+
+	if body.Error.Code != InternalError {
+		t.Errorf("Expected code 500, got %d", body.Error.Code)
+	}
+	if !strings.Contains(body.Error.Details["panic"], "nil map") {
+		t.Errorf("Expected the panic value in details, got %q", body.Error.Details["panic"])
+	}
+	if !strings.Contains(body.Error.Details["stack"], "recover_test.go") {
+		t.Errorf("Expected the stack in details, got %q", body.Error.Details["stack"])
+	}
+}
+
+func TestRecover_ErrAbortHandler(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", Recover())
+	mux.GET("/abort", func(c *Ctx) error {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler to be panicked again, got %v", rec)
+		}
+	}()
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	t.Error("Expected ServeHTTP to panic")
+}
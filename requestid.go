@@ -0,0 +1,88 @@
+package tree
+
+import (
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultRequestIDHeader is the header carrying the request ID.
+	DefaultRequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the Ctx key holding the request ID.
+	RequestIDKey = "requestID"
+
+	maxRequestIDLength = 128
+)
+
+type RequestIDConfig struct {
+	// Header read from the request and set on the response,
+	// DefaultRequestIDHeader by default.
+	Header string
+	// Generator creates the ID of requests without a valid one,
+	// uuid.NewString by default.
+	Generator func() string
+	Skipper   func(*Ctx) bool
+}
+
+// RequestID tags every request with an ID, taken from the request header
+// or created with the generator. The ID is stored under RequestIDKey, set
+// on the response header and included by Logger and DefaultErrorHandler.
+//
+// Inbound IDs longer than 128 characters or containing anything else than
+// printable ASCII are replaced, so they can't forge log lines.
+func RequestID(config ...RequestIDConfig) func(*Ctx) error {
+	cfg := UseDefaultRequestID()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Header == "" {
+		cfg.Header = DefaultRequestIDHeader
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = uuid.NewString
+	}
+
+	return func(c *Ctx) error {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return c.Next()
+		}
+
+		id := c.Header().Get(cfg.Header)
+		if !validRequestID(id) {
+			id = cfg.Generator()
+		}
+
+		c.SetKey(RequestIDKey, id)
+		c.SetHeader(cfg.Header, id)
+
+		return c.Next()
+	}
+}
+
+// RequestID returns the ID set by the RequestID middleware, or an empty
+// string when the middleware didn't run.
+func (c *Ctx) RequestID() string {
+	id, _ := c.keys[RequestIDKey].(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func UseDefaultRequestID() RequestIDConfig {
+	return RequestIDConfig{
+		Header:    DefaultRequestIDHeader,
+		Generator: uuid.NewString,
+	}
+}
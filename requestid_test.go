@@ -0,0 +1,108 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", RequestID())
+	mux.GET("/id", func(c *Ctx) error {
+		id, err := c.GetStringKey(RequestIDKey)
+		if err != nil {
+			return err
+		}
+		return c.SendString(id, OK)
+	})
+
+	tests := []struct {
+		inbound   string
+		generated bool
+	}{
+		{"", true},
+		{"client-id-1", false},
+		{"bad id\nwith newline", true},
+		{strings.Repeat("a", 129), true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/id", nil)
+		if tt.inbound != "" {
+			req.Header.Set(DefaultRequestIDHeader, tt.inbound)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		id := w.Header().Get(DefaultRequestIDHeader)
+		if w.Body.String() != id {
+			t.Errorf("%q: expected the key and the response header to match, got %q and %q", tt.inbound, w.Body.String(), id)
+		}
+
+		if tt.generated {
+			if uuid.Validate(id) != nil {
+				t.Errorf("%q: expected a generated UUID, got %q", tt.inbound, id)
+			}
+		} else if id != tt.inbound {
+			t.Errorf("%q: expected the inbound ID to be kept, got %q", tt.inbound, id)
+		}
+	}
+}
+
+func TestRequestID_CustomHeaderAndGenerator(t *testing.T) {
+	var out bytes.Buffer
+
+	mux := InitMux()
+	mux.USE("/", RequestID(RequestIDConfig{
+		Header: "X-Trace-ID",
+		Generator: func() string {
+			return "trace-1"
+		},
+	}))
+	mux.USE("/", Logger(LoggerConfig{Format: LogFormatJSON, Output: &out}))
+	mux.GET("/fail", func(c *Ctx) error {
+		return NewHTTPError(Conflict, "")
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+	if w.Header().Get("X-Trace-ID") != "trace-1" {
+		t.Errorf("Expected X-Trace-ID trace-1, got %q", w.Header().Get("X-Trace-ID"))
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error, got %q: %v", w.Body.String(), err)
+	}
+	if body["request_id"] != "trace-1" {
+		t.Errorf("Expected request_id in the error envelope, got %v", body)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", out.String(), err)
+	}
+	if entry["request_id"] != "trace-1" {
+		t.Errorf("Expected request_id to be logged, got %v", entry["request_id"])
+	}
+}
+
+func TestRequestID_TextError(t *testing.T) {
+	mux := InitMux()
+	mux.USE("/", RequestID(RequestIDConfig{Generator: func() string { return "req-7" }}))
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Body.String() != "404 Not Found (request req-7)" {
+		t.Errorf("Unexpected text error: %q", w.Body.String())
+	}
+}
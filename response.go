@@ -0,0 +1,118 @@
+package tree
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps the http.ResponseWriter of a request, keeping track
+// of the status code and the number of body bytes sent. It is owned by the
+// Ctx and reused together with it.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int64
+	written bool
+	discard bool
+}
+
+func (w *responseWriter) reset(rw http.ResponseWriter) {
+	w.ResponseWriter = rw
+	w.status = OK
+	w.size = 0
+	w.written = false
+	w.discard = false
+}
+
+// WriteHeader sends the status code once, later calls are ignored instead
+// of triggering the "superfluous WriteHeader call" warning of net/http.
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write sends the body, writing the status header first if needed. The
+// body of a HEAD request served by a GET route is discarded.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(w.status)
+	}
+
+	if w.discard {
+		return len(b), nil
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *responseWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(w.status)
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does. The
+// response counts as written once the connection is taken over.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher when the underlying writer does.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// StatusCode returns the status code of the response, 200 when it wasn't
+// set yet.
+func (c *Ctx) StatusCode() int {
+	return c.w.status
+}
+
+// BytesWritten returns the number of body bytes sent to the client. Under
+// Compress it is the compressed size, once the middleware has returned.
+func (c *Ctx) BytesWritten() int64 {
+	return c.w.size
+}
+
+// Written reports whether the status header was already sent, after which
+// the status code and headers can't be changed anymore.
+func (c *Ctx) Written() bool {
+	return c.w.written
+}
+
+// ResponseWriter returns the writer of the Ctx, which also implements
+// http.Flusher, http.Hijacker and http.Pusher when the server does.
+func (c *Ctx) ResponseWriter() http.ResponseWriter {
+	return c.w
+}
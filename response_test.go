@@ -0,0 +1,122 @@
+package tree
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCtx_ResponseState(t *testing.T) {
+	mux := InitMux()
+
+	var status int
+	var size int64
+	var written bool
+
+	mux.USE("/", func(c *Ctx) error {
+		if c.Written() {
+			t.Error("Expected the response not to be written before the handler")
+		}
+		if c.StatusCode() != OK {
+			t.Errorf("Expected default status 200, got %d", c.StatusCode())
+		}
+
+		err := c.Next()
+		status, size, written = c.StatusCode(), c.BytesWritten(), c.Written()
+		return err
+	})
+
+	mux.GET("/created", func(c *Ctx) error {
+		if err := c.SendString("created", Created); err != nil {
+			return err
+		}
+		// ignored, the status was already sent
+		return c.Status(InternalError)
+	})
+
+	req := httptest.NewRequest("GET", "/created", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != Created {
+		t.Errorf("Expected 201, got %d", w.Code)
+	}
+	if status != Created || size != int64(len("created")) || !written {
+		t.Errorf("Expected 201, 7 bytes, written; got %d, %d bytes, %v", status, size, written)
+	}
+}
+
+func TestCtx_ResponseHEADDiscardsBody(t *testing.T) {
+	mux := InitMux()
+
+	var size int64
+	mux.USE("/", func(c *Ctx) error {
+		err := c.Next()
+		size = c.BytesWritten()
+		return err
+	})
+	mux.GET("/users", func(c *Ctx) error {
+		return c.SendString("users", OK)
+	})
+
+	req := httptest.NewRequest("HEAD", "/users", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.Len() != 0 || size != 0 {
+		t.Errorf("Expected 200 without body, got %d %q (%d bytes)", w.Code, w.Body.String(), size)
+	}
+}
+
+func TestCtx_ResponseWriterPassthrough(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/stream", func(c *Ctx) error {
+		rw := c.ResponseWriter()
+
+		if _, ok := rw.(http.Flusher); !ok {
+			t.Error("Expected the writer to implement http.Flusher")
+		}
+		rw.(http.Flusher).Flush()
+
+		if _, _, err := rw.(http.Hijacker).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Expected ErrNotSupported from Hijack, got %v", err)
+		}
+		if err := rw.(http.Pusher).Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Expected ErrNotSupported from Push, got %v", err)
+		}
+
+		if err := http.NewResponseController(rw).Flush(); err != nil {
+			t.Errorf("Expected ResponseController to flush, got %v", err)
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if !w.Flushed {
+		t.Error("Expected the recorder to be flushed")
+	}
+}
+
+func TestDefaultErrorHandler_SkipsWrittenResponse(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/partial", func(c *Ctx) error {
+		if err := c.SendString("partial", OK); err != nil {
+			return err
+		}
+		return errors.New("failed after writing")
+	})
+
+	req := httptest.NewRequest("GET", "/partial", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != OK || w.Body.String() != "partial" {
+		t.Errorf("Expected the written response to be kept, got %d %q", w.Code, w.Body.String())
+	}
+}
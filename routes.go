@@ -0,0 +1,106 @@
+package tree
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/catalinfl/tree-framework/render"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name,omitempty"`
+	Middlewares []string `json:"middlewares"`
+	HandlerName string   `json:"handler"`
+}
+
+// Routes returns every registered route sorted by pattern and method.
+// Middlewares lists, in execution order, the USE middlewares whose path
+// matches the route pattern followed by the group and route middlewares.
+func (r *Mux) Routes() []RouteInfo {
+	var routes []RouteInfo
+
+	for method := GET; method <= OPTIONS; method++ {
+		handlers, ok := r.handlers[method]
+		if !ok {
+			continue
+		}
+
+		for _, rt := range *handlers {
+			pattern, err := cleanPattern(rt.path)
+			if err != nil {
+				pattern = rt.path
+			}
+
+			middlewares := r.middlewareChain(*rt, pattern)
+
+			names := make([]string, 0, len(middlewares))
+			for _, middleware := range middlewares {
+				names = append(names, funcName(middleware.Handler))
+			}
+
+			routes = append(routes, RouteInfo{
+				Method:      methodToString[method],
+				Pattern:     pattern,
+				Name:        rt.name,
+				Middlewares: names,
+				HandlerName: funcName(rt.CtxHandler),
+			})
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		mi, _ := parseMethod(routes[i].Method)
+		mj, _ := parseMethod(routes[j].Method)
+		return mi < mj
+	})
+
+	return routes
+}
+
+// RoutesHandler returns a handler serving the route table, as JSON or as a
+// plain text table when the client prefers text/plain. It is meant to be
+// mounted on a debug endpoint:
+//
+//	mux.GET("/debug/routes", mux.RoutesHandler())
+func (r *Mux) RoutesHandler() CtxFunc {
+	return func(c *Ctx) error {
+		routes := r.Routes()
+
+		if c.Accept("application/json", "text/plain") == "text/plain" {
+			c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+			c.w.WriteHeader(OK)
+
+			tw := tabwriter.NewWriter(c.w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES")
+			for _, route := range routes {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Pattern, route.Name, route.HandlerName, strings.Join(route.Middlewares, ", "))
+			}
+			return tw.Flush()
+		}
+
+		c.SetHeader("Content-Type", "application/json; charset=utf-8")
+		return c.RenderJSON(OK, render.JSON{Data: routes})
+	}
+}
+
+func funcName(fn any) string {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+
+	if f := runtime.FuncForPC(v.Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package tree
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func listUsers(c *Ctx) error { return nil }
+
+func authMiddleware(c *Ctx) error { return c.Next() }
+
+func TestMux_Routes(t *testing.T) {
+	mux := InitMux()
+
+	mux.USE("/api", CORS())
+	mux.GET("/health", listUsers)
+	api := mux.Group("/api", authMiddleware)
+	api.GET("/users", listUsers).Name("users")
+	api.POST("/users", listUsers)
+
+	routes := mux.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("Expected 3 routes, got %d", len(routes))
+	}
+
+	first := routes[0]
+	if first.Method != "GET" || first.Pattern != "/api/users" || first.Name != "users" {
+		t.Errorf("Unexpected first route: %+v", first)
+	}
+	if !strings.HasSuffix(first.HandlerName, ".listUsers") {
+		t.Errorf("Expected handler name to end with .listUsers, got %s", first.HandlerName)
+	}
+	if len(first.Middlewares) != 2 || !strings.HasSuffix(first.Middlewares[1], ".authMiddleware") {
+		t.Errorf("Expected USE and group middlewares, got %v", first.Middlewares)
+	}
+
+	if routes[1].Method != "POST" || routes[1].Pattern != "/api/users" {
+		t.Errorf("Unexpected second route: %+v", routes[1])
+	}
+
+	if routes[2].Pattern != "/health" || len(routes[2].Middlewares) != 0 {
+		t.Errorf("Unexpected third route: %+v", routes[2])
+	}
+}
+
+func TestMux_RoutesHandler(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/users/:id", listUsers).Name("user")
+	mux.GET("/debug/routes", mux.RoutesHandler())
+
+	req := httptest.NewRequest("GET", "/debug/routes", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	var routes []RouteInfo
+	if err := json.NewDecoder(w.Result().Body).Decode(&routes); err != nil {
+		t.Fatalf("Failed to decode routes: %v", err)
+	}
+	if len(routes) != 2 || routes[1].Pattern != "/users/:id" || routes[1].Name != "user" {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+
+	req = httptest.NewRequest("GET", "/debug/routes", nil)
+	req.Header.Set("Accept", "text/plain")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if !strings.HasPrefix(string(body), "METHOD") || !strings.Contains(string(body), "/users/:id") {
+		t.Errorf("Unexpected text route table: %s", string(body))
+	}
+}
@@ -3,6 +3,7 @@ package tree
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -168,3 +169,131 @@ func BenchmarkRouting_Mixed(b *testing.B) {
 		mux.ServeHTTP(w, req)
 	}
 }
+
+func BenchmarkTree_StaticLookup(b *testing.B) {
+	mux := InitMux()
+
+	handler := func(c *Ctx) error {
+		return nil
+	}
+
+	mux.GET("/", handler)
+	mux.GET("/users", handler)
+	mux.GET("/users/:id", handler)
+	mux.GET("/api/v1/users", handler)
+	mux.GET("/api/v1/posts", handler)
+	mux.GET("/api/v2/comments", handler)
+
+	root := mux.buildTrees()[GET].startNode
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if node, _ := root.InDepthSearch("/api/v1/users"); node == nil {
+			b.Fatal("route not found")
+		}
+	}
+}
+
+// newMiddlewareBenchMux registers ~300 routes and, when withMiddlewares is
+// set, 20 USE middlewares with different path prefixes.
+func newMiddlewareBenchMux(withMiddlewares bool) *Mux {
+	mux := InitMux()
+
+	handler := func(c *Ctx) error {
+		return nil
+	}
+
+	if withMiddlewares {
+		for i := 0; i < 20; i++ {
+			path := "/"
+			if i%2 == 1 {
+				path = fmt.Sprintf("/api/v%d", i)
+			}
+			mux.USE(path, func(c *Ctx) error {
+				return c.Next()
+			})
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		mux.GET(fmt.Sprintf("/api/v%d/users", i), handler)
+		mux.GET(fmt.Sprintf("/api/v%d/users/:id", i), handler)
+		mux.POST(fmt.Sprintf("/api/v%d/users", i), handler)
+	}
+
+	return mux
+}
+
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+func BenchmarkRouting_Middlewares(b *testing.B) {
+	mux := newMiddlewareBenchMux(true)
+
+	req := httptest.NewRequest("GET", "/api/v1/users/42", nil)
+	w := &discardResponseWriter{header: make(http.Header)}
+	mux.ServeHTTP(w, req)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		mux.ServeHTTP(w, req)
+	}
+}
+
+func TestRouting_MiddlewaresNoExtraAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops values under the race detector")
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/users/42", nil)
+
+	allocs := func(mux *Mux) float64 {
+		w := &discardResponseWriter{header: make(http.Header)}
+		mux.ServeHTTP(w, req)
+		return testing.AllocsPerRun(100, func() {
+			mux.ServeHTTP(w, req)
+		})
+	}
+
+	without := allocs(newMiddlewareBenchMux(false))
+	with := allocs(newMiddlewareBenchMux(true))
+
+	if with > without {
+		t.Errorf("Middleware chain adds allocations: %.0f allocs/op with middlewares, %.0f without", with, without)
+	}
+}
+
+func TestRouting_ParametersNoAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops values under the race detector")
+	}
+
+	mux := InitMux()
+
+	mux.GET("/api/v1/users/:userId/comments/:commentId", func(c *Ctx) error {
+		if _, err := c.GetURLParam("commentId"); err != nil {
+			return err
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/users/42/comments/7", nil)
+	w := &discardResponseWriter{header: make(http.Header)}
+	mux.ServeHTTP(w, req)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		mux.ServeHTTP(w, req)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations for a parameterized route, got %.0f", allocs)
+	}
+}
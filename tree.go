@@ -3,7 +3,7 @@ package tree
 import (
 	"errors"
 	"fmt"
-	"net/http"
+	"regexp"
 	"strings"
 )
 
@@ -12,13 +12,42 @@ type Tree struct {
 	startNode *Node
 }
 
+type nodeType uint8
+
+const (
+	static   nodeType = iota // compressed static prefix
+	param                    // ":name", ":name<int>", ":name|regex|" or ":|regex|", matches one whole segment
+	catchAll                 // "*name", matches the rest of the path
+)
+
+// Node is a node of a compressed radix tree. Static nodes hold the longest
+// prefix shared by all the routes below them, param nodes hold a whole
+// ":name" segment and catch-all nodes a trailing "*name" segment. Static
+// children are indexed by their first byte. Param children constrained by
+// a regex or a built-in constraint come first, the unconstrained one (if
+// any) is always the last.
 type Node struct {
-	path       string
-	children   []*Node
-	Handler    http.HandlerFunc
-	CtxHandler CtxFunc
+	path          string
+	nType         nodeType
+	route         string
+	indices       string
+	children      []*Node
+	paramChildren []*Node
+	catchAll      *Node
+	paramName     string
+	matcher       func(string) bool
+	CtxHandler    CtxFunc
+	middlewares   []Middleware
+	maxBodyBytes  int64
 }
 
+var (
+	ErrDuplicateRoute    = errors.New("duplicate route")
+	ErrConflictingParams = errors.New("conflicting parameter names")
+	ErrInvalidRoute      = errors.New("invalid route")
+	ErrCatchAllConflict  = errors.New("catch-all conflicts with sibling route")
+)
+
 func initTreesMap() map[Method]*Tree {
 	return make(map[Method]*Tree)
 }
@@ -30,170 +59,357 @@ func initTreesMap() map[Method]*Tree {
 */
 func (r *Mux) createTreeIdea(handler Route, t *Tree, m Method) (*Tree, error) {
 	if t == nil {
-		t = &Tree{method: m,
-			startNode: &Node{
-				path:       "/",
-				children:   nil,
-				Handler:    nil,
-				CtxHandler: nil,
-			}}
+		t = &Tree{method: m}
 	} // 1. tree inexistent, creates one
 
 	if t.startNode == nil {
-		t.startNode = &Node{path: "/",
-			children:   nil,
-			Handler:    nil,
-			CtxHandler: nil,
-		}
+		t.startNode = &Node{path: "/", nType: static}
 	} // 2. startNode inexistent, creates one
 
-	if handler.path == "" || handler.path == "/" {
-		// 3. check if server's route path is first - empty one
-		if t.startNode.Handler != nil {
-			return nil, errors.New(handler.path)
-		}
-		return t, nil
+	// 3. normalize and validate the server route, the root "/" is
+	// already consumed by the start node
+	pattern, err := cleanPattern(handler.path)
+	if err != nil {
+		return nil, err
 	}
+	handler.path = pattern
+
+	// 4. resolve the middleware chain once, it is kept on the route node
+	handler.middlewares = r.middlewareChain(handler, pattern)
+
+	if err := t.startNode.insert(pattern[1:], handler); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
 
-	// 4. verify all paths
-	startNode := t.startNode
-	if len(strings.Split(handler.path, "/")) >= 2 {
-		paths := strings.Split(strings.TrimSpace(handler.path), "/") // paths of server route
+// cleanPattern trims spaces and empty segments from a route pattern and
+// checks that every parameter takes a whole, named segment, that its
+// constraint is known or its regex compiles and that a catch-all is only
+// used as the last segment.
+func cleanPattern(path string) (string, error) {
+	segments := strings.Split(strings.TrimSpace(path), "/")
+	cleaned := make([]string, 0, len(segments))
 
-		for i := range paths {
-			paths[i] = strings.TrimSpace(paths[i])
+	for _, segment := range segments {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
 		}
 
-		// s := fmt.Sprintf("Method: %s, paths %s", m, paths)
-		// fmt.Println(s)
+		if len(cleaned) > 0 && cleaned[len(cleaned)-1][0] == '*' {
+			return "", fmt.Errorf("%w %s: catch-all must be the last segment", ErrInvalidRoute, path)
+		}
 
-		node := t.startNode
+		if i := strings.IndexAny(segment, ":*"); i > 0 {
+			return "", fmt.Errorf("%w %s: parameter must start the segment %q", ErrInvalidRoute, path, segment)
+		} else if i == 0 && len(segment) == 1 {
+			return "", fmt.Errorf("%w %s: parameter without a name", ErrInvalidRoute, path)
+		}
 
-		for i, path := range paths {
-			if foundNode := node.FindNodePath(path); foundNode != nil {
-				node = foundNode
-				// 5. check if the final path node already has a handler
-				if i == len(paths)-1 {
-					// if already has a handler, return error
-					if node.Handler != nil {
-						return nil, errors.New(handler.path)
-					}
-					// if not change it to true
-					node.ChangeNodeState(handler, true)
+		if segment[0] == ':' {
+			ps := parseParamSegment(segment)
+			if ps.name == "" {
+				return "", fmt.Errorf("%w %s: parameter without a name", ErrInvalidRoute, path)
+			}
+			if _, ok := paramConstraints[ps.constraint]; ps.constraint != "" && !ok {
+				return "", fmt.Errorf("%w %q in route %s", ErrUnknownConstraint, ps.constraint, path)
+			}
+			if ps.isRegex {
+				if _, err := compileParamRegex(ps.pattern); err != nil {
+					return "", fmt.Errorf("%w %s: %v", ErrInvalidRoute, path, err)
 				}
+			}
+		}
 
-			} else {
-				// path not found in tree, creating new node
-				// 6. check if the path node already exists, if not create it
-				if i == len(paths)-1 {
-					node.AddNode(handler, true, path)
-				} else {
-					// add the path into tree parent node
-					node = node.AddNode(handler, false, path)
-				}
+		cleaned = append(cleaned, segment)
+	}
+
+	return "/" + strings.Join(cleaned, "/"), nil
+}
+
+// insert adds the remaining part of a route below n. The path of n itself
+// is considered already consumed.
+func (n *Node) insert(path string, handler Route) error {
+	for path != "" {
+		if path[0] == '*' {
+			if len(n.paramChildren) > 0 {
+				return fmt.Errorf("%w: %s and %s in route %s", ErrCatchAllConflict, path, n.paramChildren[0].path, handler.path)
 			}
+
+			if n.catchAll == nil {
+				n.catchAll = &Node{path: path, nType: catchAll}
+			} else if n.catchAll.path != path {
+				return fmt.Errorf("%w: %s and %s in route %s", ErrCatchAllConflict, path, n.catchAll.path, handler.path)
+			}
+
+			n, path = n.catchAll, ""
+			continue
 		}
 
-	} else {
-		if foundNode := t.startNode.FindNodePath(handler.path); foundNode != nil {
-			// Check if the node already has a handler
-			if foundNode.Handler != nil {
-				return nil, errors.New(handler.path)
+		if path[0] == ':' {
+			end := segmentEnd(path)
+			segment := path[:end]
+
+			if n.catchAll != nil {
+				return fmt.Errorf("%w: %s and %s in route %s", ErrCatchAllConflict, n.catchAll.path, segment, handler.path)
+			}
+
+			child, err := n.addParamChild(segment)
+			if err != nil {
+				return fmt.Errorf("%w in route %s", err, handler.path)
 			}
-			foundNode.ChangeNodeState(handler, true)
-		} else {
-			t.startNode.AddNode(handler, true, handler.path)
+
+			n, path = child, path[end:]
+			continue
+		}
+
+		// static part of the path, until the next parameter
+		end := strings.IndexAny(path, ":*")
+		if end < 0 {
+			end = len(path)
 		}
+
+		idx := strings.IndexByte(n.indices, path[0])
+		if idx < 0 {
+			child := &Node{path: path[:end], nType: static}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			n, path = child, path[end:]
+			continue
+		}
+
+		child := n.children[idx]
+		i := longestCommonPrefix(path[:end], child.path)
+		if i < len(child.path) {
+			child.split(i)
+		}
+		n, path = child, path[i:]
 	}
 
-	t.startNode = startNode
-	return t, nil
+	if n.CtxHandler != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateRoute, handler.path)
+	}
+
+	n.ChangeNodeState(handler, true)
+	return nil
 }
 
-func (n *Node) ChangeNodeState(handler Route, isFinalRoute bool) {
-	if isFinalRoute {
-		n.Handler = *handler.h
+// split cuts the static node at i, moving everything after it into a new
+// child node.
+func (n *Node) split(i int) {
+	child := &Node{
+		path:          n.path[i:],
+		nType:         static,
+		route:         n.route,
+		indices:       n.indices,
+		children:      n.children,
+		paramChildren: n.paramChildren,
+		catchAll:      n.catchAll,
+		CtxHandler:    n.CtxHandler,
+		middlewares:   n.middlewares,
+		maxBodyBytes:  n.maxBodyBytes,
 	}
-}
 
-func (n *Node) AddNode(handler Route, isFinalRoute bool, segment string) *Node {
-	var child *Node
+	n.path = n.path[:i]
+	n.indices = string(child.path[0])
+	n.children = []*Node{child}
+	n.paramChildren = nil
+	n.catchAll = nil
+	n.route = ""
+	n.CtxHandler = nil
+	n.middlewares = nil
+	n.maxBodyBytes = 0
+}
 
-	if segment == "" || segment == "/" {
-		return n
+// addParamChild returns the param child for segment, creating it if needed.
+// Regexes are compiled and constraints resolved here, once, and
+// constrained params are kept before the unconstrained one so they are
+// tried first. Two unconstrained params with different names can't share
+// a position.
+func (n *Node) addParamChild(segment string) (*Node, error) {
+	for _, child := range n.paramChildren {
+		if child.path == segment {
+			return child, nil
+		}
 	}
-	if isFinalRoute {
-		child = &Node{
-			path:       segment,
-			Handler:    *handler.h,
-			CtxHandler: handler.CtxHandler,
-			children:   nil,
+
+	ps := parseParamSegment(segment)
+	child := &Node{path: segment, nType: param, paramName: ps.name}
+
+	switch {
+	case ps.isRegex:
+		re, err := compileParamRegex(ps.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRoute, segment, err)
+		}
+		child.matcher = re.MatchString
+	case ps.constraint != "":
+		matcher, ok := paramConstraints[ps.constraint]
+		if !ok {
+			return nil, fmt.Errorf("%w %q in %s", ErrUnknownConstraint, ps.constraint, segment)
 		}
-	} else {
-		child = &Node{
-			path:       segment,
-			Handler:    nil,
-			children:   nil,
-			CtxHandler: nil,
+		child.matcher = matcher
+	default:
+		if last := len(n.paramChildren) - 1; last >= 0 && n.paramChildren[last].matcher == nil {
+			return nil, fmt.Errorf("%w: %s conflicts with %s", ErrConflictingParams, segment, n.paramChildren[last].path)
 		}
+		n.paramChildren = append(n.paramChildren, child)
+		return child, nil
 	}
-	n.children = append(n.children, child)
 
-	return child
+	i := len(n.paramChildren)
+	if i > 0 && n.paramChildren[i-1].matcher == nil {
+		i--
+	}
+	n.paramChildren = append(n.paramChildren[:i], append([]*Node{child}, n.paramChildren[i:]...)...)
+
+	return child, nil
 }
 
-func (n *Node) InDepthSearch(path string) (http.HandlerFunc, CtxFunc, map[string]string) {
-	params := make(map[string]string)
-	requestPathSegments := strings.Split(strings.TrimPrefix(path, "/"), "/")
-	currentNode := n
+// paramSegment is a parsed ":..." route segment.
+type paramSegment struct {
+	name       string
+	pattern    string // regex of ":name|pattern|" or ":|pattern|"
+	constraint string // built-in constraint of ":name<constraint>"
+	isRegex    bool
+}
 
-	for _, segment := range requestPathSegments {
-		if segment == "" {
-			continue
+// parseParamSegment parses a param segment.
+//
+//	":id"         -> name "id"
+//	":id<int>"    -> name "id", constraint "int"
+//	":id|[0-9]+|" -> name "id", pattern "[0-9]+"
+//	":|[0-9]+|"   -> name "|[0-9]+|", pattern "[0-9]+" (unnamed, keyed by the regex)
+func parseParamSegment(segment string) paramSegment {
+	segment = segment[1:]
+
+	if i := strings.IndexByte(segment, '|'); i >= 0 && len(segment) >= i+2 && segment[len(segment)-1] == '|' {
+		ps := paramSegment{name: segment, pattern: segment[i+1 : len(segment)-1], isRegex: true}
+		if i > 0 {
+			ps.name = segment[:i]
 		}
+		return ps
+	}
 
-		found := false
-		for _, child := range currentNode.children {
-			if child.path == segment {
-				currentNode = child
-				found = true
-				break
-			}
+	if i := strings.IndexByte(segment, '<'); i > 0 && segment[len(segment)-1] == '>' {
+		return paramSegment{name: segment[:i], constraint: segment[i+1 : len(segment)-1]}
+	}
 
-			if strings.HasPrefix(child.path, ":") {
-				paramName := strings.TrimPrefix(child.path, ":")
-				params[paramName] = segment
-				currentNode = child
-				found = true
-				break
-			}
+	return paramSegment{name: segment}
+}
+
+// compileParamRegex compiles a param regex anchored to the whole segment.
+func compileParamRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+func (n *Node) ChangeNodeState(handler Route, isFinalRoute bool) {
+	if isFinalRoute {
+		n.route = handler.path
+		n.CtxHandler = handler.CtxHandler
+		n.middlewares = handler.middlewares
+		n.maxBodyBytes = handler.maxBodyBytes
+	}
+}
+
+// InDepthSearch looks up the node handling path. Static segments take
+// priority over parameters, which take priority over catch-alls; when a
+// branch dead-ends the search backtracks and tries the next one.
+//
+// The params map is only allocated when the route has parameters. The
+// router itself uses find, which appends to a reusable Params slice.
+func (n *Node) InDepthSearch(path string) (*Node, map[string]string) {
+	var params Params
+
+	found := n.find(path, &params)
+	if found == nil {
+		return nil, nil
+	}
+
+	if len(params) == 0 {
+		return found, nil
+	}
+
+	return found, params.Map()
+}
+
+// find is InDepthSearch appending the captured parameters to params, which
+// is left untouched when no node matches.
+func (n *Node) find(path string, params *Params) *Node {
+	path = strings.TrimPrefix(path, "/")
+	if len(path) > 0 && path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	}
+
+	return n.search(path, params)
+}
+
+func (n *Node) search(path string, params *Params) *Node {
+	if path == "" {
+		if n.CtxHandler != nil {
+			return n
 		}
+		return nil
+	}
 
-		if !found {
-			return nil, nil, nil
+	// 1. static children
+	if idx := strings.IndexByte(n.indices, path[0]); idx >= 0 {
+		child := n.children[idx]
+		if strings.HasPrefix(path, child.path) {
+			if found := child.search(path[len(child.path):], params); found != nil {
+				return found
+			}
 		}
 	}
 
-	return currentNode.Handler, currentNode.CtxHandler, params
-}
+	// 2. parameter children, each matches up to the end of the segment
+	if len(n.paramChildren) > 0 {
+		end := segmentEnd(path)
+		if end == 0 {
+			return nil
+		}
+		value := path[:end]
+
+		for _, child := range n.paramChildren {
+			if child.matcher != nil && !child.matcher(value) {
+				continue
+			}
 
-func (n *Node) FindNodePath(path string) *Node {
-	for _, node := range n.children {
-		if node.path == path {
-			return node
+			*params = append(*params, Param{Key: child.paramName, Value: value})
+
+			if found := child.search(path[end:], params); found != nil {
+				return found
+			}
+			*params = (*params)[:len(*params)-1]
 		}
 	}
 
+	// 3. catch-all child, captures the rest of the path
+	if n.catchAll != nil {
+		*params = append(*params, Param{Key: n.catchAll.path[1:], Value: path})
+
+		return n.catchAll
+	}
+
 	return nil
 }
 
-func (n *Node) FindNodePathBool(path string) bool {
-	for _, node := range n.children {
-		if node.path == path {
-			return true
-		}
+func segmentEnd(path string) int {
+	if end := strings.IndexByte(path, '/'); end >= 0 {
+		return end
 	}
-	return false
+	return len(path)
+}
+
+func longestCommonPrefix(a, b string) int {
+	max := min(len(a), len(b))
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
 }
 
 func (t *Tree) PrintTree() {
@@ -212,11 +428,17 @@ func (t *Tree) PrintTree() {
 
 func printNode(n *Node, level int) {
 	indent := strings.Repeat(" ", level)
-	fmt.Printf("%s%s (handler=%v)\n", indent, n.path, n.Handler != nil)
+	fmt.Printf("%s%s (handler=%v)\n", indent, n.path, n.CtxHandler != nil)
 
 	for _, child := range n.children {
 		printNode(child, level+1)
 	}
+	for _, child := range n.paramChildren {
+		printNode(child, level+1)
+	}
+	if n.catchAll != nil {
+		printNode(n.catchAll, level+1)
+	}
 }
 
 func (r *Mux) TestRoute() map[Method]*Tree {
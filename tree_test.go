@@ -0,0 +1,363 @@
+package tree
+
+import (
+	"errors"
+	"testing"
+)
+
+func buildTestTree(t *testing.T, routes ...string) *Tree {
+	t.Helper()
+
+	mux := InitMux()
+	var tree *Tree
+	for _, route := range routes {
+		path := route
+		handler := Route{
+			path: path,
+			CtxHandler: func(c *Ctx) error {
+				return c.SendString(path, 200)
+			},
+		}
+
+		var err error
+		tree, err = mux.createTreeIdea(handler, tree, GET)
+		if err != nil {
+			t.Fatalf("Failed to add route %s: %v", route, err)
+		}
+	}
+
+	return tree
+}
+
+func TestTree_StaticPriority(t *testing.T) {
+	// registration order must not matter
+	orders := [][]string{
+		{"/users/:id", "/users/profile", "/users/profile/edit"},
+		{"/users/profile/edit", "/users/profile", "/users/:id"},
+	}
+
+	for _, routes := range orders {
+		tree := buildTestTree(t, routes...)
+
+		tests := []struct {
+			path  string
+			route string
+			param string
+		}{
+			{"/users/profile", "/users/profile", ""},
+			{"/users/profile/edit", "/users/profile/edit", ""},
+			{"/users/123", "/users/:id", "123"},
+			{"/users/prof", "/users/:id", "prof"},
+			{"/users/profiles", "/users/:id", "profiles"},
+		}
+
+		for _, tt := range tests {
+			node, params := tree.startNode.InDepthSearch(tt.path)
+			if node == nil {
+				t.Errorf("%v: no route found for %s", routes, tt.path)
+				continue
+			}
+			if node.route != tt.route {
+				t.Errorf("%v: expected %s to match %s, got %s", routes, tt.path, tt.route, node.route)
+			}
+			if params["id"] != tt.param {
+				t.Errorf("%v: expected id=%q for %s, got %q", routes, tt.param, tt.path, params["id"])
+			}
+		}
+	}
+}
+
+func TestTree_Backtracking(t *testing.T) {
+	tree := buildTestTree(t,
+		"/files/static/readme",
+		"/files/:name/download",
+	)
+
+	node, params := tree.startNode.InDepthSearch("/files/static/download")
+	if node == nil || node.route != "/files/:name/download" {
+		t.Fatalf("Expected backtracking into /files/:name/download, got %v", node)
+	}
+	if params["name"] != "static" {
+		t.Errorf("Expected name=static, got %q", params["name"])
+	}
+
+	if node, _ := tree.startNode.InDepthSearch("/files/static/unknown"); node != nil {
+		t.Errorf("Expected no match, got %s", node.route)
+	}
+}
+
+func TestTree_Compression(t *testing.T) {
+	tree := buildTestTree(t, "/api/v1/users", "/api/v1/posts", "/api/v2/comments")
+
+	root := tree.startNode
+	if len(root.children) != 1 || root.children[0].path != "api/v" {
+		t.Fatalf("Expected a single compressed 'api/v' child, got %+v", root.children)
+	}
+
+	for _, path := range []string{"/api/v1/users", "/api/v1/posts", "/api/v2/comments"} {
+		if node, _ := tree.startNode.InDepthSearch(path); node == nil || node.route != path {
+			t.Errorf("Expected %s to match", path)
+		}
+	}
+
+	for _, path := range []string{"/api", "/api/v", "/api/v1", "/api/v3/comments"} {
+		if node, _ := tree.startNode.InDepthSearch(path); node != nil {
+			t.Errorf("Expected %s not to match, got %s", path, node.route)
+		}
+	}
+}
+
+func TestTree_RootAndTrailingSlash(t *testing.T) {
+	tree := buildTestTree(t, "/", "/users")
+
+	if node, _ := tree.startNode.InDepthSearch("/"); node == nil || node.route != "/" {
+		t.Error("Expected root route to match")
+	}
+	if node, _ := tree.startNode.InDepthSearch("/users/"); node == nil || node.route != "/users" {
+		t.Error("Expected trailing slash to be ignored")
+	}
+}
+
+func TestTree_Errors(t *testing.T) {
+	mux := InitMux()
+	handler := func(c *Ctx) error { return nil }
+
+	tests := []struct {
+		routes []string
+		err    error
+	}{
+		{[]string{"/users", "/users/"}, ErrDuplicateRoute},
+		{[]string{"/users/:id", "/users/:name/posts"}, ErrConflictingParams},
+		{[]string{"/users/user:id"}, ErrInvalidRoute},
+		{[]string{"/users/:"}, ErrInvalidRoute},
+	}
+
+	for _, tt := range tests {
+		var tree *Tree
+		var err error
+		for _, route := range tt.routes {
+			tree, err = mux.createTreeIdea(Route{path: route, CtxHandler: handler}, tree, GET)
+			if err != nil {
+				break
+			}
+		}
+
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v: expected error %v, got %v", tt.routes, tt.err, err)
+		}
+	}
+}
+
+func TestTree_CatchAll(t *testing.T) {
+	tree := buildTestTree(t,
+		"/static/*filepath",
+		"/static/index.html",
+		"/static/images/:name",
+	)
+
+	tests := []struct {
+		path     string
+		route    string
+		filepath string
+	}{
+		{"/static/index.html", "/static/index.html", ""},
+		{"/static/css/app.css", "/static/*filepath", "css/app.css"},
+		{"/static/app.js", "/static/*filepath", "app.js"},
+		{"/static/images/logo.png", "/static/images/:name", ""},
+		{"/static/images/icons/logo.png", "/static/*filepath", "images/icons/logo.png"},
+	}
+
+	for _, tt := range tests {
+		node, params := tree.startNode.InDepthSearch(tt.path)
+		if node == nil {
+			t.Errorf("No route found for %s", tt.path)
+			continue
+		}
+		if node.route != tt.route {
+			t.Errorf("Expected %s to match %s, got %s", tt.path, tt.route, node.route)
+		}
+		if params["filepath"] != tt.filepath {
+			t.Errorf("Expected filepath=%q for %s, got %q", tt.filepath, tt.path, params["filepath"])
+		}
+	}
+
+	if node, _ := tree.startNode.InDepthSearch("/static"); node != nil {
+		t.Errorf("Expected catch-all not to match an empty remainder, got %s", node.route)
+	}
+}
+
+func TestTree_CatchAllErrors(t *testing.T) {
+	mux := InitMux()
+	handler := func(c *Ctx) error { return nil }
+
+	tests := []struct {
+		routes []string
+		err    error
+	}{
+		{[]string{"/files/*path", "/files/:name"}, ErrCatchAllConflict},
+		{[]string{"/files/:name", "/files/*path"}, ErrCatchAllConflict},
+		{[]string{"/files/*path", "/files/*other"}, ErrCatchAllConflict},
+		{[]string{"/files/*path", "/files/*path"}, ErrDuplicateRoute},
+		{[]string{"/files/*path/edit"}, ErrInvalidRoute},
+		{[]string{"/files/*"}, ErrInvalidRoute},
+	}
+
+	for _, tt := range tests {
+		var tree *Tree
+		var err error
+		for _, route := range tt.routes {
+			tree, err = mux.createTreeIdea(Route{path: route, CtxHandler: handler}, tree, GET)
+			if err != nil {
+				break
+			}
+		}
+
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v: expected error %v, got %v", tt.routes, tt.err, err)
+		}
+	}
+}
+
+func TestTree_RegexParams(t *testing.T) {
+	tree := buildTestTree(t,
+		"/users/:id|[0-9]+|",
+		"/users/:name",
+		"/users/:code|[A-Z]{2}\\d{4}|/orders",
+		"/files/:|[a-z]+\\.txt|",
+	)
+
+	tests := []struct {
+		path   string
+		route  string
+		params map[string]string
+	}{
+		{"/users/123", "/users/:id|[0-9]+|", map[string]string{"id": "123"}},
+		{"/users/john", "/users/:name", map[string]string{"name": "john"}},
+		{"/users/123abc", "/users/:name", map[string]string{"name": "123abc"}},
+		{"/users/AB1234/orders", "/users/:code|[A-Z]{2}\\d{4}|/orders", map[string]string{"code": "AB1234"}},
+		{"/files/readme.txt", "/files/:|[a-z]+\\.txt|", map[string]string{"|[a-z]+\\.txt|": "readme.txt"}},
+		{"/files/readme.md", "", nil},
+		{"/users/AB12/orders", "", nil},
+	}
+
+	for _, tt := range tests {
+		node, params := tree.startNode.InDepthSearch(tt.path)
+		if tt.route == "" {
+			if node != nil {
+				t.Errorf("Expected %s not to match, got %s", tt.path, node.route)
+			}
+			continue
+		}
+
+		if node == nil {
+			t.Errorf("No route found for %s", tt.path)
+			continue
+		}
+		if node.route != tt.route {
+			t.Errorf("Expected %s to match %s, got %s", tt.path, tt.route, node.route)
+		}
+		if len(params) != len(tt.params) {
+			t.Errorf("Expected params %v for %s, got %v", tt.params, tt.path, params)
+		}
+		for k, v := range tt.params {
+			if params[k] != v {
+				t.Errorf("Expected %s=%q for %s, got %q", k, v, tt.path, params[k])
+			}
+		}
+	}
+}
+
+func TestTree_InvalidRegex(t *testing.T) {
+	mux := InitMux()
+
+	_, err := mux.createTreeIdea(Route{path: "/users/:id|[0-9+|", CtxHandler: func(c *Ctx) error { return nil }}, nil, GET)
+	if !errors.Is(err, ErrInvalidRoute) {
+		t.Errorf("Expected ErrInvalidRoute for an invalid regex, got %v", err)
+	}
+}
+
+func TestParseParamSegment(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    paramSegment
+	}{
+		{":id", paramSegment{name: "id"}},
+		{":id<int>", paramSegment{name: "id", constraint: "int"}},
+		{":id|[0-9]+|", paramSegment{name: "id", pattern: "[0-9]+", isRegex: true}},
+		{":|\\d+|", paramSegment{name: "|\\d+|", pattern: "\\d+", isRegex: true}},
+		{":a|b", paramSegment{name: "a|b"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseParamSegment(tt.segment); got != tt.want {
+			t.Errorf("parseParamSegment(%q) = %+v, expected %+v", tt.segment, got, tt.want)
+		}
+	}
+}
+
+func TestTree_Constraints(t *testing.T) {
+	tree := buildTestTree(t,
+		"/orders/:id<int>",
+		"/orders/:slug<slug>",
+		"/users/:uid<uuid>",
+		"/users/:name",
+	)
+
+	tests := []struct {
+		path  string
+		route string
+	}{
+		{"/orders/42", "/orders/:id<int>"},
+		{"/orders/-7", "/orders/:id<int>"},
+		{"/orders/summer-sale", "/orders/:slug<slug>"},
+		{"/orders/Summer_Sale", ""},
+		{"/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "/users/:uid<uuid>"},
+		{"/users/john", "/users/:name"},
+	}
+
+	for _, tt := range tests {
+		node, params := tree.startNode.InDepthSearch(tt.path)
+		if tt.route == "" {
+			if node != nil {
+				t.Errorf("Expected %s not to match, got %s", tt.path, node.route)
+			}
+			continue
+		}
+		if node == nil || node.route != tt.route {
+			t.Errorf("Expected %s to match %s, got %v", tt.path, tt.route, node)
+			continue
+		}
+		if len(params) != 1 {
+			t.Errorf("Expected a single param for %s, got %v", tt.path, params)
+		}
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	tests := []struct {
+		constraint string
+		valid      []string
+		invalid    []string
+	}{
+		{"int", []string{"0", "-12", "9223372036854775807"}, []string{"", "1.5", "abc", "9223372036854775808"}},
+		{"uint", []string{"0", "18"}, []string{"-1", "x"}},
+		{"uuid", []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, []string{"6ba7b8109dad11d180b400c04fd430c8", "nope"}},
+		{"slug", []string{"a", "hello-world-2"}, []string{"", "-a", "a-", "a--b", "Hello"}},
+		{"alpha", []string{"abcXYZ"}, []string{"", "ab1", "a-b"}},
+		{"alnum", []string{"abc123XYZ"}, []string{"", "a_b", "a-b"}},
+	}
+
+	for _, tt := range tests {
+		matcher := paramConstraints[tt.constraint]
+		for _, v := range tt.valid {
+			if !matcher(v) {
+				t.Errorf("%s: expected %q to be valid", tt.constraint, v)
+			}
+		}
+		for _, v := range tt.invalid {
+			if matcher(v) {
+				t.Errorf("%s: expected %q to be invalid", tt.constraint, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrRouteNotFound      = errors.New("route not found")
+	ErrURLParamsMismatch  = errors.New("wrong number of url parameters")
+	ErrInvalidURLParam    = errors.New("invalid url parameter")
+	ErrDuplicateRouteName = errors.New("duplicate route name")
+)
+
+// Name names the route so its URL can be rebuilt with Mux.URL or
+// Ctx.URLFor. Route names must be unique within a Mux.
+func (rt *Route) Name(name string) *Route {
+	if existing, ok := rt.mux.namedRoutes[name]; ok && existing != rt {
+		panic(fmt.Sprintf("%s: %q is used by %s and %s", ErrDuplicateRouteName, name, existing.path, rt.path))
+	}
+
+	if rt.name != "" {
+		delete(rt.mux.namedRoutes, rt.name)
+	}
+
+	segments, err := parseURLSegments(rt.path)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+
+	rt.name = name
+	rt.segments = segments
+	rt.mux.namedRoutes[name] = rt
+	return rt
+}
+
+// urlSegment is a segment of the pattern of a named route. Name parses the
+// pattern and compiles its regexes once, Mux.URL only fills the params.
+type urlSegment struct {
+	text     string
+	param    bool
+	catchAll bool
+	match    func(string) bool
+}
+
+func parseURLSegments(path string) ([]urlSegment, error) {
+	pattern, err := cleanPattern(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []urlSegment
+	for _, segment := range strings.Split(pattern[1:], "/") {
+		switch {
+		case segment == "":
+			continue
+		case segment[0] == '*':
+			segments = append(segments, urlSegment{text: segment, param: true, catchAll: true})
+		case segment[0] == ':':
+			seg := urlSegment{text: segment, param: true}
+			if ps := parseParamSegment(segment); ps.isRegex {
+				re, err := compileParamRegex(ps.pattern)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRoute, segment, err)
+				}
+				seg.match = re.MatchString
+			} else if ps.constraint != "" {
+				seg.match = paramConstraints[ps.constraint]
+			}
+			segments = append(segments, seg)
+		default:
+			segments = append(segments, urlSegment{text: segment})
+		}
+	}
+
+	return segments, nil
+}
+
+// URL builds the path of the route registered under name. The params fill
+// the dynamic segments of the route pattern in order and are validated
+// against them: regex and constrained segments must match and only a
+// catch-all may contain slashes.
+//
+//	mux.GET("/users/:id/files/*path", h).Name("file")
+//	mux.URL("file", "42", "docs/a.txt") // "/users/42/files/docs/a.txt"
+func (r *Mux) URL(name string, params ...string) (string, error) {
+	rt, ok := r.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+
+	var b strings.Builder
+	b.Grow(len(rt.path))
+	used := 0
+
+	for _, segment := range rt.segments {
+		b.WriteByte('/')
+
+		if !segment.param {
+			b.WriteString(segment.text)
+			continue
+		}
+
+		if used >= len(params) {
+			return "", fmt.Errorf("%w: route %s expects more than %d", ErrURLParamsMismatch, name, len(params))
+		}
+		value := params[used]
+		used++
+
+		if value == "" {
+			return "", fmt.Errorf("%w: empty value for %s", ErrInvalidURLParam, segment.text)
+		}
+
+		if segment.catchAll {
+			parts := strings.Split(value, "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			b.WriteString(strings.Join(parts, "/"))
+			continue
+		}
+
+		if strings.Contains(value, "/") {
+			return "", fmt.Errorf("%w: %q for %s contains a slash", ErrInvalidURLParam, value, segment.text)
+		}
+
+		if segment.match != nil && !segment.match(value) {
+			return "", fmt.Errorf("%w: %q does not match %s", ErrInvalidURLParam, value, segment.text)
+		}
+
+		b.WriteString(url.PathEscape(value))
+	}
+
+	if used != len(params) {
+		return "", fmt.Errorf("%w: route %s expects %d, got %d", ErrURLParamsMismatch, name, used, len(params))
+	}
+
+	if b.Len() == 0 {
+		return "/", nil
+	}
+
+	return b.String(), nil
+}
+
+// URLFor builds the path of a named route of the Mux serving the request.
+func (c *Ctx) URLFor(name string, params ...string) (string, error) {
+	if c.mux == nil {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+
+	return c.mux.URL(name, params...)
+}
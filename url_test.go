@@ -0,0 +1,111 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_URL(t *testing.T) {
+	mux := InitMux()
+	handler := func(c *Ctx) error { return nil }
+
+	mux.GET("/", handler).Name("home")
+	mux.GET("/users/:id", handler).Name("user")
+	mux.GET("/products/:|[A-Z]{2}\\d{4}|", handler).Name("product")
+	mux.GET("/static/*filepath", handler).Name("static")
+	mux.GET("/orders/:id|[0-9]+|", handler).Name("order")
+	mux.Group("/api/v1").GET("/posts/:postId/comments", handler).Name("comments")
+
+	tests := []struct {
+		name     string
+		params   []string
+		expected string
+		err      error
+	}{
+		{"home", nil, "/", nil},
+		{"user", []string{"42"}, "/users/42", nil},
+		{"user", []string{"john doe"}, "/users/john%20doe", nil},
+		{"product", []string{"AB1234"}, "/products/AB1234", nil},
+		{"static", []string{"css/app.css"}, "/static/css/app.css", nil},
+		{"comments", []string{"7"}, "/api/v1/posts/7/comments", nil},
+		{"user", nil, "", ErrURLParamsMismatch},
+		{"user", []string{"1", "2"}, "", ErrURLParamsMismatch},
+		{"user", []string{"a/b"}, "", ErrInvalidURLParam},
+		{"user", []string{""}, "", ErrInvalidURLParam},
+		{"product", []string{"ab12"}, "", ErrInvalidURLParam},
+		{"order", []string{"15"}, "/orders/15", nil},
+		{"order", []string{"abc"}, "", ErrInvalidURLParam},
+		{"missing", nil, "", ErrRouteNotFound},
+	}
+
+	for _, tt := range tests {
+		got, err := mux.URL(tt.name, tt.params...)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("URL(%q, %v): expected error %v, got %v", tt.name, tt.params, tt.err, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("URL(%q, %v) = %q, expected %q", tt.name, tt.params, got, tt.expected)
+		}
+	}
+}
+
+func TestMux_URLPrecompiled(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not reliable under the race detector")
+	}
+
+	mux := InitMux()
+	mux.GET("/orders/:id|[0-9]+|/items/:n<int>", func(c *Ctx) error { return nil }).Name("item")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := mux.URL("item", "15", "3"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// only the builder allocates, the regex is compiled by Name
+	if allocs > 1 {
+		t.Errorf("Expected URL not to parse the pattern again, got %v allocs", allocs)
+	}
+}
+
+func TestRoute_NameDuplicate(t *testing.T) {
+	mux := InitMux()
+	handler := func(c *Ctx) error { return nil }
+
+	mux.GET("/a", handler).Name("same")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for duplicate route name")
+		}
+	}()
+	mux.GET("/b", handler).Name("same")
+}
+
+func TestCtx_URLFor(t *testing.T) {
+	mux := InitMux()
+
+	mux.GET("/users/:id", func(c *Ctx) error {
+		return nil
+	}).Name("user")
+	mux.GET("/links", func(c *Ctx) error {
+		link, err := c.URLFor("user", "5")
+		if err != nil {
+			return err
+		}
+		return c.SendString(link, 200)
+	})
+
+	req := httptest.NewRequest("GET", "/links", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != "/users/5" {
+		t.Errorf("Expected '/users/5', got '%s'", string(body))
+	}
+}